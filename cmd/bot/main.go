@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"log"
 
 	"github.com/joho/godotenv"
@@ -19,17 +20,25 @@ func main() {
 		log.Fatalln("cannot load .env files")
 	}
 
-	container, err := sqlstore.New("sqlite3", "file:bot.db?_foreign_keys=on&_journal_mode=WAL&_timeout=5000", nil)
+	db, err := sql.Open("sqlite3", "file:bot.db?_foreign_keys=on&_journal_mode=WAL&_timeout=5000")
 	if err != nil {
 		log.Fatalf("DB error: %v", err)
 	}
 
+	container := sqlstore.NewWithDB(db, "sqlite3", nil)
+	if err := container.Upgrade(); err != nil {
+		log.Fatalf("DB upgrade error: %v", err)
+	}
+
 	device, err := container.GetFirstDevice()
 	if err != nil {
 		log.Fatalf("Device error: %v", err)
 	}
 
-	botInstance := bot.NewBot(device, waLog.Stdout("BOT", "INFO", true))
+	botInstance, err := bot.NewBot(device, db, waLog.Stdout("BOT", "INFO", true))
+	if err != nil {
+		log.Fatalf("Bot init error: %v", err)
+	}
 	log.Println("Starting bot...")
 	botInstance.Run()
 }
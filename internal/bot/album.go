@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	albumMaxItems    = 30
+	albumConcurrency = 4
+)
+
+type albumItem struct {
+	msg       *waE2E.Message
+	mediaType MediaType
+	err       error
+}
+
+// handleSendAlbum expects SEND_ALBUM:<jid>|<caption>|<url>|<url>...
+func (b *Bot) handleSendAlbum(meta requestMeta, msg string) {
+	parts := strings.Split(strings.TrimPrefix(msg, "SEND_ALBUM:"), "|")
+	if len(parts) < 3 {
+		b.Log.Errorf("Invalid SEND_ALBUM format")
+		return
+	}
+
+	jid, err := types.ParseJID(parts[0])
+	if err != nil {
+		b.Log.Errorf("JID parse error: %v", err)
+		return
+	}
+
+	caption := strings.ReplaceAll(parts[1], "{{NL}}", "\n")
+	urls := parts[2:]
+	if len(urls) > albumMaxItems {
+		b.Log.Warnf("Album has %d items, only sending the first %d", len(urls), albumMaxItems)
+		urls = urls[:albumMaxItems]
+	}
+
+	b.jobs.Submit(JobMedia, meta, msg, func() error {
+		err := b.sendAlbum(jid, urls, caption)
+		if err != nil {
+			b.Log.Errorf("Album send error: %v", err)
+		}
+		return err
+	}, func(err error) {
+		b.Log.Errorf("Album for %s rejected: %v", jid, err)
+	})
+}
+
+func (b *Bot) sendAlbum(jid types.JID, urls []string, caption string) error {
+	items := b.prepareAlbumItems(urls)
+
+	var images, videos uint32
+	ready := make([]albumItem, 0, len(items))
+	for i, item := range items {
+		if item.err != nil {
+			b.Log.Warnf("Skipping album item %d: %v", i+1, item.err)
+			continue
+		}
+		if item.mediaType == MediaVideo {
+			videos++
+		} else {
+			images++
+		}
+		ready = append(ready, item)
+	}
+
+	if len(ready) == 0 {
+		return fmt.Errorf("no album items could be prepared")
+	}
+
+	// The caption goes on the first item that made it, so a failed first
+	// download doesn't drop it.
+	if first := ready[0].msg; first.GetImageMessage() != nil {
+		first.ImageMessage.Caption = proto.String(caption)
+	} else if first.GetVideoMessage() != nil {
+		first.VideoMessage.Caption = proto.String(caption)
+	}
+
+	// A single item doesn't need the album wrapper.
+	if len(ready) == 1 {
+		_, err := b.send(jid, ready[0].msg)
+		return err
+	}
+
+	resp, err := b.send(jid, &waE2E.Message{
+		AlbumMessage: &waE2E.AlbumMessage{
+			ExpectedImageCount: proto.Uint32(images),
+			ExpectedVideoCount: proto.Uint32(videos),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("album header failed: %w", err)
+	}
+
+	parentKey := &waCommon.MessageKey{
+		RemoteJID: proto.String(jid.String()),
+		FromMe:    proto.Bool(true),
+		ID:        proto.String(resp.ID),
+	}
+
+	for i, item := range ready {
+		item.msg.MessageContextInfo = &waE2E.MessageContextInfo{
+			MessageAssociation: &waE2E.MessageAssociation{
+				AssociationType:  waE2E.MessageAssociation_MEDIA_ALBUM.Enum(),
+				ParentMessageKey: parentKey,
+				MessageIndex:     proto.Int32(int32(i)),
+			},
+		}
+
+		if _, err := b.send(jid, item.msg); err != nil {
+			return fmt.Errorf("album item %d failed: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// prepareAlbumItems downloads and uploads every URL with bounded concurrency,
+// keeping the results in the original order.
+func (b *Bot) prepareAlbumItems(urls []string) []albumItem {
+	items := make([]albumItem, len(urls))
+	sem := make(chan struct{}, albumConcurrency)
+	var wg sync.WaitGroup
+
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			items[i] = b.prepareAlbumItem(strings.TrimSpace(url))
+		}(i, url)
+	}
+
+	wg.Wait()
+	return items
+}
+
+func (b *Bot) prepareAlbumItem(url string) albumItem {
+	data, contentType, err := b.fetcher.Fetch(url, "")
+	if err != nil {
+		return albumItem{err: err}
+	}
+
+	var mediaType MediaType
+	switch {
+	case matchesMediaType(MediaImage, contentType):
+		mediaType = MediaImage
+	case matchesMediaType(MediaVideo, contentType):
+		mediaType = MediaVideo
+	default:
+		return albumItem{err: fmt.Errorf("unsupported album content type %s", contentType)}
+	}
+
+	msg, err := b.uploadMedia(data, mediaType, "", nil)
+	if err != nil {
+		return albumItem{err: err}
+	}
+
+	return albumItem{msg: msg, mediaType: mediaType}
+}
@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moo-d/AwaraBot/internal/scraper"
+	"go.mau.fi/whatsmeow/types"
+)
+
+type batchItem struct {
+	Index    int    `json:"index"`
+	URL      string `json:"url"`
+	Title    string `json:"title,omitempty"`
+	Duration int    `json:"duration,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+type batchJob struct {
+	id       string
+	chat     types.JID
+	format   string
+	items    []*batchItem
+	skipped  []*batchItem
+	maxTotal int
+
+	mu       sync.Mutex
+	consumed int
+}
+
+// handleBatchDownload expects BATCH_DOWNLOAD:<jid>|<format>|<url>[|<url>...]
+// where a single playlist URL is expanded into its entries. The batch runs as
+// one download job whose request ID doubles as the batch's jobId.
+func (b *Bot) handleBatchDownload(meta requestMeta, msg string) {
+	parts := strings.Split(msg[len("BATCH_DOWNLOAD:"):], "|")
+	if len(parts) < 3 {
+		b.Log.Errorf("Invalid batch download format")
+		return
+	}
+
+	jid, err := types.ParseJID(parts[0])
+	if err != nil {
+		b.Log.Errorf("JID parse error: %v", err)
+		return
+	}
+
+	format := parts[1]
+	if format != "mp3" {
+		format = "mp4"
+	}
+
+	if meta.ID == "" {
+		meta.ID = fmt.Sprintf("batch-%d", time.Now().UnixNano())
+	}
+	if meta.Chat.IsEmpty() {
+		meta.Chat = jid
+	}
+
+	b.jobs.Submit(JobDownload, meta, msg, func() error {
+		return b.runBatchDownload(meta.ID, jid, format, parts[2:])
+	}, func(err error) {
+		b.sendBatchError(meta.ID, jid.String(), err)
+	})
+}
+
+func (b *Bot) sendBatchError(jobID, chat string, err error) {
+	b.sendEvent(BotEvent{
+		Type: "batch_error",
+		Content: map[string]interface{}{
+			"jobId":     jobID,
+			"requestId": jobID,
+			"chat":      chat,
+			"error":     err.Error(),
+		},
+	})
+}
+
+func (b *Bot) runBatchDownload(id string, chat types.JID, format string, urls []string) error {
+	job := &batchJob{
+		id:       id,
+		chat:     chat,
+		format:   format,
+		maxTotal: envInt("YT_BATCH_MAX_DURATION", 3600),
+	}
+
+	entries, err := b.expandBatchURLs(urls)
+	if err != nil {
+		b.sendBatchError(job.id, chat.String(), err)
+		return err
+	}
+
+	maxItems := envInt("YT_BATCH_MAX_ITEMS", 25)
+	for _, entry := range entries {
+		item := &batchItem{
+			Index:    len(job.items) + len(job.skipped) + 1,
+			URL:      entry.URL,
+			Title:    entry.Title,
+			Duration: entry.Duration,
+		}
+		switch {
+		case maxItems > 0 && len(job.items) >= maxItems:
+			item.Status = "skipped"
+			item.Error = fmt.Sprintf("over the %d item limit", maxItems)
+			job.skipped = append(job.skipped, item)
+		case !job.reserve(item.Duration):
+			item.Status = "skipped"
+			item.Error = fmt.Sprintf("over the %ds total duration limit", job.maxTotal)
+			job.skipped = append(job.skipped, item)
+		default:
+			item.Status = "queued"
+			job.items = append(job.items, item)
+		}
+	}
+
+	b.sendEvent(BotEvent{
+		Type: "batch_started",
+		Content: map[string]interface{}{
+			"jobId":   job.id,
+			"chat":    chat.String(),
+			"total":   len(job.items),
+			"skipped": len(job.skipped),
+		},
+	})
+
+	sem := make(chan struct{}, max(1, envInt("YT_BATCH_CONCURRENCY", 2)))
+	var wg sync.WaitGroup
+	for _, item := range job.items {
+		wg.Add(1)
+		go func(item *batchItem) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			item.Status = "running"
+			b.sendBatchProgress(job, item)
+
+			if err := b.processBatchItem(job, item); err != nil {
+				item.Status = "failed"
+				item.Error = err.Error()
+			} else {
+				item.Status = "done"
+			}
+			b.sendBatchProgress(job, item)
+		}(item)
+	}
+	wg.Wait()
+
+	succeeded, failed := 0, 0
+	for _, item := range job.items {
+		if item.Status == "done" {
+			succeeded++
+		} else {
+			failed++
+		}
+	}
+
+	b.sendEvent(BotEvent{
+		Type: "batch_done",
+		Content: map[string]interface{}{
+			"jobId":     job.id,
+			"chat":      chat.String(),
+			"total":     len(job.items),
+			"succeeded": succeeded,
+			"failed":    failed,
+			"skipped":   len(job.skipped),
+			"items":     append(job.items, job.skipped...),
+		},
+	})
+	if succeeded == 0 && failed > 0 {
+		return fmt.Errorf("all %d batch items failed", failed)
+	}
+	return nil
+}
+
+// reserve books seconds against the job's duration budget. Unknown durations
+// (0) are always accepted here and checked again once the download resolves.
+func (j *batchJob) reserve(seconds int) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.maxTotal > 0 && j.consumed+seconds > j.maxTotal {
+		return false
+	}
+	j.consumed += seconds
+	return true
+}
+
+func (b *Bot) expandBatchURLs(urls []string) ([]scraper.PlaylistEntry, error) {
+	var entries []scraper.PlaylistEntry
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+
+		if scraper.ExtractPlaylistID(url) != "" && !strings.Contains(url, "watch?v=") {
+			playlist, err := b.YouTubeScraper.Playlist(url)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, playlist.Entries...)
+			continue
+		}
+
+		entries = append(entries, scraper.PlaylistEntry{URL: url})
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("no URLs to download")
+	}
+	return entries, nil
+}
+
+func (b *Bot) processBatchItem(job *batchJob, item *batchItem) error {
+	mediaType := MediaVideo
+	if job.format == "mp3" {
+		mediaType = MediaAudio
+	}
+
+	res, err := b.youtubeDownload(item.URL, job.format, "")
+	if err != nil {
+		return err
+	}
+
+	if item.Title == "" {
+		item.Title = res.Title
+	}
+	if item.Duration == 0 {
+		item.Duration = int(res.Time * 60)
+		if !job.reserve(item.Duration) {
+			return fmt.Errorf("over the %ds total duration limit", job.maxTotal)
+		}
+	}
+
+	meta := requestMeta{ID: fmt.Sprintf("%s#%d", job.id, item.Index), Chat: job.chat}
+	data, _, err := b.fetcher.FetchWithProgress(res.URL, mediaType, b.newProgress(meta, "download"))
+	if err != nil {
+		return err
+	}
+
+	return b.uploadAndSendMedia(job.chat, data, mediaType, item.Title, b.newProgress(meta, "upload"))
+}
+
+func (b *Bot) sendBatchProgress(job *batchJob, item *batchItem) {
+	b.sendEvent(BotEvent{
+		Type: "batch_progress",
+		Content: map[string]interface{}{
+			"jobId": job.id,
+			"chat":  job.chat.String(),
+			"total": len(job.items),
+			"item":  *item,
+		},
+	})
+}
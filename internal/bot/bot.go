@@ -1,9 +1,11 @@
 package bot
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/moo-d/AwaraBot/internal/scraper"
 	"go.mau.fi/whatsmeow"
@@ -14,13 +16,30 @@ import (
 )
 
 type Bot struct {
-	Client         *whatsmeow.Client
-	Log            waLog.Logger
-	retryCount     int
-	TikTokScraper  *scraper.TikTokScraper
-	YouTubeScraper *scraper.YouTubeScraper
-	GPTScraper     *scraper.GPTScraper
-	VyroScraper    *scraper.VyroScraper
+	Client           *whatsmeow.Client
+	Log              waLog.Logger
+	retryCount       int
+	TikTokScraper    *scraper.TikTokScraper
+	YouTubeScraper   *scraper.YouTubeScraper
+	GPTScraper       *scraper.GPTScraper
+	VyroScraper      *scraper.VyroScraper
+	InstagramScraper *scraper.InstagramScraper
+	TwitterScraper   *scraper.TwitterScraper
+	FacebookScraper  *scraper.FacebookScraper
+	SpotifyScraper   *scraper.SpotifyScraper
+	ImageSearch      *scraper.ImageSearch
+	fetcher          *Fetcher
+	polls            *pollStore
+	cache            *cacheStore
+	jobs             *jobScheduler
+	outbox           *outbox
+	tags             *tagStore
+	schedules        *scheduleStore
+	router           *commandRouter
+	chatHistory      *chatHistories
+	plugins          []*plugin
+	perms            *permissions
+	resumeOnce       sync.Once
 }
 
 type BotEvent struct {
@@ -28,13 +47,57 @@ type BotEvent struct {
 	Content map[string]interface{} `json:"content"`
 }
 
-func NewBot(device *store.Device, logger waLog.Logger) *Bot {
+func NewBot(device *store.Device, db *sql.DB, logger waLog.Logger) (*Bot, error) {
+	cache, err := newCacheStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("cache init failed: %w", err)
+	}
+
+	jobs, err := newJobStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("job store init failed: %w", err)
+	}
+
+	tags, err := newTagStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("tag store init failed: %w", err)
+	}
+
+	schedules, err := newScheduleStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("schedule store init failed: %w", err)
+	}
+
+	polls, err := newPollStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("poll store init failed: %w", err)
+	}
+
+	roles, err := newRoleStore(db)
+	if err != nil {
+		return nil, fmt.Errorf("role store init failed: %w", err)
+	}
+
 	b := &Bot{
 		Log:           logger,
 		TikTokScraper: scraper.NewTikTokScraper(),
+		fetcher:       newFetcherFromEnv(),
+		polls:         polls,
+		cache:         cache,
+		tags:          tags,
+		schedules:     schedules,
+		router:        newCommandRouterFromEnv(),
+		chatHistory:   newChatHistories(),
+		perms:         newPermissionsFromEnv(roles, logger),
+	}
+	b.jobs = newJobScheduler(b, jobs)
+	b.outbox = newOutboxFromEnv(b)
+	if err := b.registerBuiltinCommands(); err != nil {
+		return nil, err
 	}
+	b.loadPlugins()
 	b.initClient(device)
-	return b
+	return b, nil
 }
 
 func (b *Bot) initClient(device *store.Device) {
@@ -52,6 +115,15 @@ func (b *Bot) initClient(device *store.Device) {
 	b.YouTubeScraper = scraper.NewYouTubeScraper()
 	b.GPTScraper = scraper.NewGPTScraper()
 	b.VyroScraper = scraper.NewVyroScraper()
+	b.InstagramScraper = scraper.NewInstagramScraper()
+	b.TwitterScraper = scraper.NewTwitterScraper()
+	b.FacebookScraper = scraper.NewFacebookScraper()
+	// Facebook pages are fetched from user supplied links, so they go through
+	// the same address guard as media fetches
+	b.FacebookScraper.SetTransport(b.fetcher.client.Transport)
+	b.SpotifyScraper = scraper.NewSpotifyScraper(b.YouTubeScraper)
+	b.ImageSearch = scraper.NewImageSearch(scraper.NewPinterestScraper())
+	b.ImageSearch.SafeSearch = os.Getenv("IMAGE_SAFE_SEARCH") != "off"
 }
 
 func (b *Bot) sendEvent(event BotEvent) {
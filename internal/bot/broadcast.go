@@ -0,0 +1,290 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const tagsSchema = `
+CREATE TABLE IF NOT EXISTS awara_tags (
+	tag TEXT NOT NULL,
+	jid TEXT NOT NULL,
+	PRIMARY KEY (tag, jid)
+)`
+
+// tagStore groups chats under names that broadcasts can target.
+type tagStore struct {
+	db *sql.DB
+}
+
+func newTagStore(db *sql.DB) (*tagStore, error) {
+	if _, err := db.Exec(tagsSchema); err != nil {
+		return nil, err
+	}
+	return &tagStore{db: db}, nil
+}
+
+func (s *tagStore) Add(tag string, jids []string) error {
+	for _, jid := range jids {
+		if _, err := s.db.Exec(`INSERT OR IGNORE INTO awara_tags (tag, jid) VALUES (?, ?)`, tag, jid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *tagStore) Remove(tag string, jids []string) error {
+	for _, jid := range jids {
+		if _, err := s.db.Exec(`DELETE FROM awara_tags WHERE tag = ? AND jid = ?`, tag, jid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *tagStore) Members(tag string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT jid FROM awara_tags WHERE tag = ? ORDER BY jid`, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jids []string
+	for rows.Next() {
+		var jid string
+		if err := rows.Scan(&jid); err != nil {
+			return nil, err
+		}
+		jids = append(jids, jid)
+	}
+	return jids, rows.Err()
+}
+
+// handleTag expects TAG:<add|remove|list>|<tag>[|<jid>,<jid>...]
+func (b *Bot) handleTag(msg string) {
+	parts := strings.SplitN(strings.TrimPrefix(msg, "TAG:"), "|", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		b.Log.Errorf("Invalid TAG format")
+		return
+	}
+
+	action, tag := parts[0], strings.ToLower(strings.TrimSpace(parts[1]))
+	var jids []string
+	if len(parts) > 2 {
+		for _, jid := range strings.Split(parts[2], ",") {
+			if jid = strings.TrimSpace(jid); jid != "" {
+				jids = append(jids, jid)
+			}
+		}
+	}
+
+	var err error
+	switch action {
+	case "add":
+		err = b.tags.Add(tag, jids)
+	case "remove":
+		err = b.tags.Remove(tag, jids)
+	case "list":
+	default:
+		err = fmt.Errorf("unknown tag action %q", action)
+	}
+
+	response := map[string]interface{}{
+		"type":   "tag_result",
+		"status": err == nil,
+		"tag":    tag,
+	}
+	if err == nil {
+		members, listErr := b.tags.Members(tag)
+		err = listErr
+		response["members"] = members
+	}
+	if err != nil {
+		response["status"] = false
+		response["error"] = err.Error()
+	}
+	b.writeResult("TAG_RESULT", response)
+}
+
+type broadcastTarget struct {
+	JID    string `json:"jid"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// handleBroadcast expects BROADCAST:<targets>|<type>|<content>[|<caption>]
+// where targets is a comma separated list of JIDs, "groups", "contacts" or
+// "tag:<name>", type is text, image, video, gif or audio, and content is the
+// text or a media URL.
+func (b *Bot) handleBroadcast(meta requestMeta, msg string) {
+	parts := strings.SplitN(strings.TrimPrefix(msg, "BROADCAST:"), "|", 4)
+	if len(parts) < 3 {
+		b.Log.Errorf("Invalid BROADCAST format")
+		return
+	}
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+
+	if meta.ID == "" {
+		meta.ID = fmt.Sprintf("broadcast-%d", time.Now().UnixNano())
+	}
+
+	b.jobs.Submit(JobBroadcast, meta, msg, func() error {
+		return b.runBroadcast(meta, parts[0], MediaType(parts[1]), parts[2], strings.ReplaceAll(parts[3], "{{NL}}", "\n"))
+	}, func(err error) {
+		b.sendBroadcastError(meta, err)
+	})
+}
+
+func (b *Bot) runBroadcast(meta requestMeta, targetSpec string, kind MediaType, content, caption string) error {
+	targets, err := b.resolveBroadcastTargets(targetSpec)
+	if err != nil {
+		b.sendBroadcastError(meta, err)
+		return err
+	}
+
+	message, err := b.buildBroadcastMessage(meta, kind, content, caption)
+	if err != nil {
+		b.sendBroadcastError(meta, err)
+		return err
+	}
+
+	b.sendEvent(BotEvent{
+		Type: "broadcast_started",
+		Content: map[string]interface{}{
+			"requestId": meta.ID,
+			"chat":      meta.Chat.String(),
+			"total":     len(targets),
+		},
+	})
+
+	results := make([]broadcastTarget, 0, len(targets))
+	succeeded := 0
+	for _, target := range targets {
+		result := broadcastTarget{JID: target.String(), Status: "sent"}
+		// the outbox paces these; each target gets its own copy since
+		// SendMessage may fill in per-message fields
+		if _, err := b.send(target, proto.Clone(message).(*waProto.Message)); err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+		} else {
+			succeeded++
+		}
+		results = append(results, result)
+	}
+
+	b.sendEvent(BotEvent{
+		Type: "broadcast_done",
+		Content: map[string]interface{}{
+			"requestId": meta.ID,
+			"chat":      meta.Chat.String(),
+			"total":     len(targets),
+			"succeeded": succeeded,
+			"failed":    len(targets) - succeeded,
+			"results":   results,
+		},
+	})
+	return nil
+}
+
+// buildBroadcastMessage prepares the message once; media is downloaded and
+// uploaded a single time and the same reference is sent to every target.
+func (b *Bot) buildBroadcastMessage(meta requestMeta, kind MediaType, content, caption string) (*waProto.Message, error) {
+	switch kind {
+	case "text":
+		text := strings.ReplaceAll(content, "{{NL}}", "\n")
+		if text == "" {
+			return nil, errors.New("broadcast text is empty")
+		}
+		return &waProto.Message{Conversation: proto.String(text)}, nil
+	case MediaImage, MediaVideo, MediaGIF, MediaAudio:
+		data, _, err := b.fetcher.FetchWithProgress(content, kind, b.newProgress(meta, "download"))
+		if err != nil {
+			return nil, err
+		}
+		return b.uploadMedia(data, kind, caption, b.newProgress(meta, "upload"))
+	}
+	return nil, fmt.Errorf("unsupported broadcast type %q", kind)
+}
+
+func (b *Bot) resolveBroadcastTargets(spec string) ([]types.JID, error) {
+	seen := make(map[types.JID]bool)
+	var targets []types.JID
+	add := func(jid types.JID) {
+		if !jid.IsEmpty() && !seen[jid] {
+			seen[jid] = true
+			targets = append(targets, jid)
+		}
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case entry == "groups":
+			groups, err := b.Client.GetJoinedGroups()
+			if err != nil {
+				return nil, fmt.Errorf("failed to list groups: %w", err)
+			}
+			for _, group := range groups {
+				add(group.JID)
+			}
+		case entry == "contacts":
+			contacts, err := b.Client.Store.Contacts.GetAllContacts()
+			if err != nil {
+				return nil, fmt.Errorf("failed to list contacts: %w", err)
+			}
+			var saved []types.JID
+			for jid, info := range contacts {
+				if jid.Server == types.DefaultUserServer && info.Found && (info.FullName != "" || info.FirstName != "") {
+					saved = append(saved, jid)
+				}
+			}
+			sort.Slice(saved, func(i, j int) bool { return saved[i].User < saved[j].User })
+			for _, jid := range saved {
+				add(jid)
+			}
+		case strings.HasPrefix(entry, "tag:"):
+			members, err := b.tags.Members(strings.ToLower(strings.TrimPrefix(entry, "tag:")))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read tag: %w", err)
+			}
+			for _, member := range members {
+				if jid, err := types.ParseJID(member); err == nil {
+					add(jid)
+				}
+			}
+		default:
+			jid, err := types.ParseJID(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid target %q: %w", entry, err)
+			}
+			add(jid)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("broadcast has no targets")
+	}
+	return targets, nil
+}
+
+func (b *Bot) sendBroadcastError(meta requestMeta, err error) {
+	b.sendEvent(BotEvent{
+		Type: "broadcast_error",
+		Content: map[string]interface{}{
+			"requestId": meta.ID,
+			"chat":      meta.Chat.String(),
+			"error":     err.Error(),
+		},
+	})
+}
@@ -0,0 +1,167 @@
+package bot
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/moo-d/AwaraBot/internal/scraper"
+)
+
+const cacheSchema = `
+CREATE TABLE IF NOT EXISTS awara_cache (
+	namespace  TEXT    NOT NULL,
+	key        TEXT    NOT NULL,
+	value      TEXT    NOT NULL,
+	expires_at INTEGER NOT NULL,
+	PRIMARY KEY (namespace, key)
+)`
+
+// cacheStore is a small TTL key/value table in the bot database. Values are
+// stored as JSON so any result type can be cached.
+type cacheStore struct {
+	db          *sql.DB
+	downloadTTL time.Duration
+	uploadTTL   time.Duration
+}
+
+func newCacheStore(db *sql.DB) (*cacheStore, error) {
+	if _, err := db.Exec(cacheSchema); err != nil {
+		return nil, err
+	}
+
+	c := &cacheStore{
+		db:          db,
+		downloadTTL: time.Duration(envInt("DOWNLOAD_CACHE_TTL", 3600)) * time.Second,
+		uploadTTL:   time.Duration(envInt("UPLOAD_CACHE_TTL", 6*3600)) * time.Second,
+	}
+	c.purgeExpired()
+	return c, nil
+}
+
+// Get decodes the cached value into v and reports whether a live entry existed.
+func (c *cacheStore) Get(namespace, key string, v interface{}) bool {
+	var value string
+	err := c.db.QueryRow(
+		`SELECT value FROM awara_cache WHERE namespace = ? AND key = ? AND expires_at > ?`,
+		namespace, key, time.Now().Unix(),
+	).Scan(&value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal([]byte(value), v) == nil
+}
+
+func (c *cacheStore) Put(namespace, key string, v interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(
+		`INSERT OR REPLACE INTO awara_cache (namespace, key, value, expires_at) VALUES (?, ?, ?, ?)`,
+		namespace, key, string(value), time.Now().Add(ttl).Unix(),
+	)
+	return err
+}
+
+func (c *cacheStore) Delete(namespace, key string) {
+	c.db.Exec(`DELETE FROM awara_cache WHERE namespace = ? AND key = ?`, namespace, key)
+}
+
+func (c *cacheStore) purgeExpired() {
+	c.db.Exec(`DELETE FROM awara_cache WHERE expires_at <= ?`, time.Now().Unix())
+}
+
+// youtubeDownload resolves a YouTube link through the download cache. DOWNLOAD:
+// requests, the ytmp3/ytmp4 commands and batch items share one entry per video,
+// format and quality, so playlists that overlap with earlier requests skip
+// savetube entirely. An empty quality is resolved to the scraper's default
+// first, so it shares the entry of an explicit request for that quality.
+func (b *Bot) youtubeDownload(link, format, quality string) (*scraper.DownloadResult, error) {
+	if format != "mp3" {
+		format = "mp4"
+	}
+	if quality == "" {
+		quality = scraper.DefaultVideoQuality
+		if format == "mp3" {
+			quality = scraper.DefaultAudioQuality
+		}
+	}
+
+	key := downloadCacheKey("youtube", link, format, quality)
+	var res scraper.DownloadResult
+	if b.cache.Get("download", key, &res) {
+		return &res, nil
+	}
+
+	var result *scraper.DownloadResult
+	var err error
+	if format == "mp3" {
+		result, err = b.YouTubeScraper.Audio(link, quality, maxMediaSize)
+	} else {
+		result, err = b.YouTubeScraper.Video(link, quality, maxMediaSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !result.Status {
+		return nil, errors.New(result.Message)
+	}
+
+	if err := b.cache.Put("download", key, result, b.cache.downloadTTL); err != nil {
+		b.Log.Warnf("Failed to cache download result: %v", err)
+	}
+	return result, nil
+}
+
+// downloadCacheKey identifies a download independently of how the link was
+// shared. For services with an ID extractor (YouTube, Instagram, X, Spotify)
+// tracking parameters, mobile hosts and short links of the same item map to
+// the same key. Other links are keyed by host and path without the www./m.
+// prefixes, so a TikTok or Facebook short link (vm.tiktok.com, fb.watch) is
+// cached separately from the full URL it redirects to.
+func downloadCacheKey(service, link, format, quality string) string {
+	id := normalizeMediaURL(service, link)
+	return fmt.Sprintf("%s:%s:%s:%s", service, id, format, quality)
+}
+
+func normalizeMediaURL(service, link string) string {
+	var id string
+	var err error
+
+	switch service {
+	case "youtube":
+		id, err = scraper.ExtractYouTubeID(link)
+	case "instagram", "ig":
+		id, err = scraper.ExtractInstagramShortcode(link)
+	case "twitter", "x":
+		id, err = scraper.ExtractTweetID(link)
+	case "spotify":
+		id, err = scraper.ExtractSpotifyTrackID(link)
+	default:
+		err = fmt.Errorf("no ID extractor")
+	}
+	if err == nil {
+		return id
+	}
+
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(link)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "mobile.", "web."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	return host + strings.TrimSuffix(u.EscapedPath(), "/")
+}
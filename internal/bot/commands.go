@@ -0,0 +1,550 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moo-d/AwaraBot/internal/scraper"
+)
+
+var startedAt = time.Now()
+
+// maxCommandVideoSize matches MAX_VIDEO_SIZE in the TS TikTok command.
+const maxCommandVideoSize = 64 << 20
+
+// registerBuiltinCommands adds the Go versions of the commands the TS layer ships,
+// followed by any added with RegisterCommand.
+func (b *Bot) registerBuiltinCommands() error {
+	commands := []Command{
+		&FuncCommand{
+			CommandInfo: CommandInfo{
+				Name:        "menu",
+				Aliases:     []string{"help"},
+				Category:    "main",
+				Description: "Show all available commands",
+				Usage:       "[command]",
+			},
+			Run: b.runMenu,
+		},
+		&FuncCommand{
+			CommandInfo: CommandInfo{
+				Name:        "stats",
+				Aliases:     []string{"stat"},
+				Category:    "main",
+				Description: "Check bot statistics",
+			},
+			Run: b.runStats,
+		},
+		downloadCommand("tiktok", []string{"tt", "tiktokdl"}, "Download TikTok video or images without watermark", b.runTikTok),
+		downloadCommand("instagram", []string{"ig", "igdl"}, "Download Instagram posts, reels, carousels and public stories", b.runInstagram),
+		downloadCommand("twitter", []string{"x", "twdl", "xdl"}, "Download videos, GIFs and images from a tweet", b.runTwitter),
+		downloadCommand("facebook", []string{"fb", "fbdl"}, "Download Facebook videos", b.runFacebook),
+		downloadCommand("spotify", []string{"sp", "spdl"}, "Download a Spotify track", b.runSpotify),
+		downloadCommand("ytmp3", []string{"ytaudio", "yta"}, "Download YouTube audio", b.runYouTube("mp3")),
+		downloadCommand("ytmp4", []string{"ytvideo", "ytv"}, "Download YouTube video", b.runYouTube("mp4")),
+		&FuncCommand{
+			CommandInfo: CommandInfo{
+				Name:        "remini",
+				Aliases:     []string{"hd", "hdr", "enhance"},
+				Category:    "tools",
+				Description: "Enhance image quality using AI",
+				Usage:       "[reply to an image]",
+				Wait:        true,
+				Kind:        JobEnhance,
+			},
+			Run: b.runEnhance,
+		},
+		&FuncCommand{
+			CommandInfo: CommandInfo{
+				Name:        "ai",
+				Aliases:     []string{"gpt", "chat"},
+				Category:    "ai",
+				Description: "Ask the AI assistant",
+				Usage:       "<question>",
+				Kind:        JobChatbot,
+			},
+			Run: b.runChatbot,
+		},
+		&FuncCommand{
+			CommandInfo: CommandInfo{
+				Name:        "role",
+				Aliases:     []string{"roles"},
+				Category:    "owner",
+				Description: "Grant, revoke and list user roles; \"here\" limits a role to this chat",
+				Usage:       "grant|revoke <number> <role> [here] | list [number]",
+				Role:        RoleOwner,
+			},
+			Run: b.runRole,
+		},
+	}
+
+	for _, cmd := range append(commands, registeredCommands()...) {
+		if err := b.router.Register(cmd); err != nil {
+			return fmt.Errorf("register %s: %w", cmd.Info().Name, err)
+		}
+	}
+	return nil
+}
+
+func downloadCommand(name string, aliases []string, description string, run func(*CommandContext, string) error) Command {
+	return &FuncCommand{
+		CommandInfo: CommandInfo{
+			Name:        name,
+			Aliases:     aliases,
+			Category:    "downloader",
+			Description: description,
+			Usage:       "<url>",
+			Wait:        true,
+			Kind:        JobDownload,
+		},
+		Run: func(ctx *CommandContext) error {
+			if len(ctx.Args) == 0 {
+				return &UsageError{Reason: "Please provide a URL"}
+			}
+			return run(ctx, ctx.Args[0])
+		},
+	}
+}
+
+func (b *Bot) runMenu(ctx *CommandContext) error {
+	if len(ctx.Args) > 0 {
+		found := b.router.Lookup(ctx.Args[0])
+		if found == nil {
+			return &UsageError{Reason: fmt.Sprintf("Unknown command %s", ctx.Args[0])}
+		}
+		cmd := found.Info()
+		help := fmt.Sprintf("*%s%s* %s\n%s", ctx.Prefix, cmd.Name, cmd.Usage, cmd.Description)
+		if len(cmd.Aliases) > 0 {
+			help += "\nAliases: " + strings.Join(cmd.Aliases, ", ")
+		}
+		return ctx.Reply(help)
+	}
+
+	categories := b.router.Categories()
+	names := make([]string, 0, len(categories))
+	for category := range categories {
+		names = append(names, category)
+	}
+	sort.Strings(names)
+
+	var menu strings.Builder
+	menu.WriteString("╭━━━〔 *вoт мenυ* 〕━━━╮\n\n")
+	for _, category := range names {
+		fmt.Fprintf(&menu, "❏ *%s*\n", strings.ToUpper(category))
+		for _, cmd := range categories[category] {
+			menu.WriteString("• " + cmd.Name)
+			if len(cmd.Aliases) > 0 {
+				menu.WriteString(" (" + strings.Join(cmd.Aliases, ", ") + ")")
+			}
+			menu.WriteString("\n")
+		}
+		menu.WriteString("\n")
+	}
+	menu.WriteString("╰━━━━━━━━━━━━━━━━━━━╯\n")
+	menu.WriteString("> _Type /help <command> for more info_")
+	return ctx.Reply(menu.String())
+}
+
+func (b *Bot) runStats(ctx *CommandContext) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return ctx.Reply(strings.TrimSpace(fmt.Sprintf(`
+╭━━━〔 📊 BOT STATISTICS 〕━━━╮
+│
+│  🔹 Bot Status:
+│  ├ ⏳ Uptime Bot: %s
+│  ├ 📂 Memory Usage: %.2f MB
+│  ├ 🧵 Goroutines: %d
+│
+│  📜 Additional Info:
+│  ├ 🌐 Platform: %s
+│  ├ 🏷 Arch: %s
+│  ├ ⚡ CPUs: %d
+│  ├ 🐹 Go: %s
+│
+╰━━━━━━━━━━━━━━━━━━━╯`,
+		time.Since(startedAt).Round(time.Second),
+		float64(mem.Sys)/(1<<20),
+		runtime.NumGoroutine(),
+		runtime.GOOS,
+		runtime.GOARCH,
+		runtime.NumCPU(),
+		runtime.Version(),
+	)))
+}
+
+// cachedDownload returns the scraper result for link, sharing cache entries
+// with DOWNLOAD: requests for the same service.
+func cachedDownload[T any](b *Bot, service, link string, download func() (T, error)) (T, error) {
+	key := downloadCacheKey(service, link, "", "")
+	var result T
+	if b.cache.Get("download", key, &result) {
+		return result, nil
+	}
+
+	result, err := download()
+	if err != nil {
+		return result, err
+	}
+	if err := b.cache.Put("download", key, result, b.cache.downloadTTL); err != nil {
+		b.Log.Warnf("Failed to cache download result: %v", err)
+	}
+	return result, nil
+}
+
+type commandMedia struct {
+	url       string
+	mediaType MediaType
+}
+
+// sendMediaItems sends items as an album when there are several images and
+// videos, and one by one otherwise. caption goes on the first item.
+func (c *CommandContext) sendMediaItems(items []commandMedia, caption string) error {
+	if len(items) == 0 {
+		return fmt.Errorf("no media found")
+	}
+
+	album := len(items) > 1
+	urls := make([]string, 0, len(items))
+	for _, item := range items {
+		album = album && (item.mediaType == MediaImage || item.mediaType == MediaVideo)
+		urls = append(urls, item.url)
+	}
+	if album {
+		return c.Bot.sendAlbum(c.Chat, urls, caption)
+	}
+
+	for i, item := range items {
+		if i > 0 {
+			caption = ""
+		}
+		if err := c.SendMedia(item.url, item.mediaType, caption); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatCount(n int64) string {
+	switch {
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1fK", float64(n)/1e3)
+	}
+	return fmt.Sprint(n)
+}
+
+func (b *Bot) runTikTok(ctx *CommandContext, link string) error {
+	if !strings.Contains(link, "tiktok.com") {
+		return &UsageError{Reason: "Invalid TikTok URL"}
+	}
+	res, err := cachedDownload(b, "tiktok", link, func() (*scraper.TikTokResult, error) {
+		return b.TikTokScraper.DownloadVideo(link)
+	})
+	if err != nil {
+		return err
+	}
+
+	caption := res.Title
+	if res.Author != "" {
+		caption += fmt.Sprintf("\n👤 @%s", res.Author)
+	}
+	caption += fmt.Sprintf("\n▶️ %s  ❤️ %s  💬 %s  🔁 %s",
+		formatCount(res.PlayCount), formatCount(res.LikeCount), formatCount(res.CommentCount), formatCount(res.ShareCount))
+	caption = strings.TrimSpace(caption)
+
+	var items []commandMedia
+	switch {
+	case len(res.Images) > 0:
+		for _, image := range res.Images {
+			items = append(items, commandMedia{image, MediaImage})
+		}
+	case res.HD != "" && res.HDSize > 0 && res.HDSize <= maxCommandVideoSize:
+		items = append(items, commandMedia{res.HD, MediaVideo})
+	case res.Video != "":
+		items = append(items, commandMedia{res.Video, MediaVideo})
+	}
+	if err := ctx.sendMediaItems(items, caption); err != nil {
+		return err
+	}
+
+	if res.Music != "" {
+		return ctx.SendMedia(res.Music, MediaAudio, "")
+	}
+	return nil
+}
+
+func (b *Bot) runInstagram(ctx *CommandContext, link string) error {
+	res, err := cachedDownload(b, "instagram", link, func() (*scraper.InstagramResult, error) {
+		return b.InstagramScraper.Download(link)
+	})
+	if err != nil {
+		return err
+	}
+
+	items := make([]commandMedia, 0, len(res.Items))
+	for _, item := range res.Items {
+		items = append(items, commandMedia{item.URL, MediaType(item.Type)})
+	}
+	return ctx.sendMediaItems(items, res.Title)
+}
+
+func (b *Bot) runTwitter(ctx *CommandContext, link string) error {
+	res, err := cachedDownload(b, "twitter", link, func() (*scraper.TwitterResult, error) {
+		return b.TwitterScraper.Download(link)
+	})
+	if err != nil {
+		return err
+	}
+
+	items := make([]commandMedia, 0, len(res.Items))
+	for _, item := range res.Items {
+		items = append(items, commandMedia{item.URL, MediaType(item.Type)})
+	}
+	return ctx.sendMediaItems(items, res.Title)
+}
+
+func (b *Bot) runFacebook(ctx *CommandContext, link string) error {
+	res, err := cachedDownload(b, "facebook", link, func() (*scraper.FacebookResult, error) {
+		return b.FacebookScraper.Download(link)
+	})
+	if err != nil {
+		return err
+	}
+
+	video := res.HD
+	if video == "" {
+		video = res.Video
+	}
+	return ctx.SendMedia(video, MediaVideo, res.Title)
+}
+
+func (b *Bot) runSpotify(ctx *CommandContext, link string) error {
+	res, err := cachedDownload(b, "spotify", link, func() (*scraper.SpotifyResult, error) {
+		return b.SpotifyScraper.Download(link, maxMediaSize)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Reply(fmt.Sprintf("🎵 %s - %s", res.Title, strings.Join(res.Artists, ", "))); err != nil {
+		return err
+	}
+	return ctx.SendMedia(res.URL, MediaAudio, "")
+}
+
+func (b *Bot) runYouTube(format string) func(*CommandContext, string) error {
+	mediaType := MediaVideo
+	if format == "mp3" {
+		mediaType = MediaAudio
+	}
+
+	return func(ctx *CommandContext, link string) error {
+		if _, err := scraper.ExtractYouTubeID(link); err != nil {
+			return &UsageError{Reason: "Invalid YouTube URL"}
+		}
+		res, err := b.youtubeDownload(link, format, "")
+		if err != nil {
+			return err
+		}
+
+		if mediaType == MediaAudio {
+			if err := ctx.Reply("🎵 " + res.Title); err != nil {
+				return err
+			}
+		}
+		return ctx.SendMedia(res.URL, mediaType, res.Title)
+	}
+}
+
+func (b *Bot) runEnhance(ctx *CommandContext) error {
+	image, err := ctx.Image()
+	if err != nil {
+		return err
+	}
+
+	enhanced, err := b.VyroScraper.EnhanceImage(image, "enhance")
+	if err != nil {
+		return err
+	}
+	return b.uploadAndSendMedia(ctx.Chat, enhanced, MediaImage, "Here's your enhanced image", b.newProgress(ctx.meta, "upload"))
+}
+
+// chatHistories keeps the recent conversation of each sender for the native
+// chatbot, like chatHistories in src/index.ts.
+type chatHistories struct {
+	mu       sync.Mutex
+	limit    int
+	messages map[string][]scraper.Message
+}
+
+func (h *chatHistories) add(sender string, messages ...scraper.Message) []scraper.Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history := append(h.messages[sender], messages...)
+	if len(history) > h.limit {
+		history = history[len(history)-h.limit:]
+	}
+	h.messages[sender] = history
+	return append([]scraper.Message(nil), history...)
+}
+
+func newChatHistories() *chatHistories {
+	return &chatHistories{
+		limit:    max(1, envInt("CHATBOT_HISTORY", 20)),
+		messages: make(map[string][]scraper.Message),
+	}
+}
+
+var (
+	chatbotInstructions     []scraper.Message
+	chatbotInstructionsOnce sync.Once
+)
+
+// loadInstructions reads the same instruction files as src/utils/instructionLoader.ts.
+func loadInstructions() []scraper.Message {
+	chatbotInstructionsOnce.Do(func() {
+		dir := os.Getenv("INSTRUCTIONS_DIR")
+		if dir == "" {
+			dir = "../instructions"
+		}
+		for _, name := range []string{"personality.txt", "capabilities.txt", "response_format.txt"} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err == nil && len(data) > 0 {
+				chatbotInstructions = append(chatbotInstructions, scraper.Message{Role: "user", Content: string(data)})
+			}
+		}
+	})
+	return chatbotInstructions
+}
+
+func (b *Bot) runChatbot(ctx *CommandContext) error {
+	prompt := ctx.ArgText()
+	if prompt == "" {
+		return &UsageError{Reason: "Please ask something"}
+	}
+
+	model := os.Getenv("CHATBOT_MODEL")
+	if model == "" {
+		model = "GPT-4"
+	}
+
+	sender := ctx.Sender.String()
+	history := b.chatHistory.add(sender, scraper.Message{Role: "user", Content: prompt})
+	result, err := b.GPTScraper.Chat(prompt, append(loadInstructions(), history...), model)
+	if err != nil {
+		return err
+	}
+
+	// The model answers with {"cmd", "caption", "query"} when it wants to run a command.
+	var reply struct {
+		Cmd     string `json:"cmd"`
+		Caption string `json:"caption"`
+		Query   string `json:"query"`
+	}
+	if json.Unmarshal([]byte(result.Message), &reply) != nil {
+		reply.Caption = result.Message
+	}
+
+	if reply.Caption != "" {
+		b.chatHistory.add(sender, scraper.Message{Role: "assistant", Content: reply.Caption})
+		if err := ctx.Reply(reply.Caption); err != nil {
+			return err
+		}
+	}
+
+	name := strings.TrimPrefix(reply.Cmd, "/")
+	if name == "" {
+		return nil
+	}
+	if cmd := b.router.Lookup(name); cmd == nil || cmd == ctx.Command {
+		b.Log.Warnf("Chatbot asked for unusable command %q", reply.Cmd)
+		return nil
+	}
+
+	meta := ctx.meta
+	meta.ID += "-" + name
+	b.dispatchCommand(ctx.Event, meta, strings.TrimSpace(name+" "+reply.Query))
+	return nil
+}
+
+func (b *Bot) runRole(ctx *CommandContext) error {
+	if len(ctx.Args) == 0 {
+		return &UsageError{Reason: "Missing action"}
+	}
+
+	switch action := strings.ToLower(ctx.Args[0]); action {
+	case "grant", "revoke":
+		if len(ctx.Args) < 3 {
+			return &UsageError{Reason: "Missing user or role"}
+		}
+		user, err := parseUserJID(ctx.Args[1])
+		if err != nil {
+			return &UsageError{Reason: err.Error()}
+		}
+		// grants are keyed by phone number, also when @mentioned by LID
+		user = b.phoneJID(ctx.Chat, user)
+		role := strings.ToLower(ctx.Args[2])
+		if err := validateRoleName(role); err != nil {
+			return &UsageError{Reason: err.Error()}
+		}
+		chat, scope := "", "everywhere"
+		if len(ctx.Args) > 3 && strings.EqualFold(ctx.Args[3], "here") {
+			chat, scope = ctx.Chat.String(), "in this chat"
+		}
+
+		if action == "grant" {
+			if err := b.perms.roles.Grant(user.String(), chat, role, b.phoneJID(ctx.Chat, ctx.Sender).String()); err != nil {
+				return err
+			}
+			return ctx.Reply(fmt.Sprintf("✅ %s is now %s %s", user.User, role, scope))
+		}
+		found, err := b.perms.roles.Revoke(user.String(), chat, role)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ctx.Reply(fmt.Sprintf("%s is not %s %s", user.User, role, scope))
+		}
+		return ctx.Reply(fmt.Sprintf("✅ %s is no longer %s %s", user.User, role, scope))
+	case "list":
+		jid := ""
+		if len(ctx.Args) > 1 {
+			user, err := parseUserJID(ctx.Args[1])
+			if err != nil {
+				return &UsageError{Reason: err.Error()}
+			}
+			jid = b.phoneJID(ctx.Chat, user).String()
+		}
+		grants, err := b.perms.roles.List(jid)
+		if err != nil {
+			return err
+		}
+		if len(grants) == 0 {
+			return ctx.Reply("No roles granted")
+		}
+
+		var list strings.Builder
+		list.WriteString("👥 *Roles*\n")
+		for _, g := range grants {
+			user, _ := parseUserJID(g.JID)
+			fmt.Fprintf(&list, "• %s: %s", user.User, g.Role)
+			if g.Chat != "" {
+				fmt.Fprintf(&list, " (in %s)", g.Chat)
+			}
+			list.WriteString("\n")
+		}
+		return ctx.Reply(strings.TrimSpace(list.String()))
+	}
+	return &UsageError{Reason: fmt.Sprintf("Unknown action %s", ctx.Args[0])}
+}
@@ -0,0 +1,21 @@
+package bot
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envInt reads an integer from the environment, falling back to def when unset or invalid.
+func envInt(key string, def int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
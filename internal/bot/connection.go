@@ -40,6 +40,10 @@ func (b *Bot) connectWithQR() error {
 func (b *Bot) onConnected(evt *events.Connected) {
 	b.retryCount = 0
 	b.Log.Infof("Connected successfully")
+	b.resumeOnce.Do(func() {
+		go b.resumeJobs()
+		go b.runSchedules()
+	})
 
 	if b.Client.Store.PushName == "" {
 		if name := os.Getenv("BOT_NAME"); name != "" {
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// vCardEscaper escapes the characters vCard 3.0 treats as syntax in text values.
+var vCardEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// phoneDigits strips a phone number down to the digits WhatsApp uses as waid.
+func phoneDigits(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+func buildVCard(name, phone string) string {
+	digits := phoneDigits(phone)
+	return fmt.Sprintf(
+		"BEGIN:VCARD\nVERSION:3.0\nFN:%s\nTEL;type=CELL;type=VOICE;waid=%s:+%s\nEND:VCARD",
+		vCardEscaper.Replace(name), digits, digits,
+	)
+}
+
+// parseVCardPhones returns the phone numbers listed in the TEL lines of a vCard.
+func parseVCardPhones(vcard string) []string {
+	var phones []string
+	for _, line := range strings.Split(strings.ReplaceAll(vcard, "\r\n", "\n"), "\n") {
+		if !strings.HasPrefix(strings.ToUpper(line), "TEL") {
+			continue
+		}
+		if idx := strings.LastIndex(line, ":"); idx != -1 {
+			phones = append(phones, strings.TrimSpace(line[idx+1:]))
+		}
+	}
+	return phones
+}
+
+// handleSendContact expects SEND_CONTACT:<jid>|<name>|<phone>[|<name>|<phone>...].
+// A single pair is sent as a contact card, several pairs as a contact array.
+func (b *Bot) handleSendContact(msg string) {
+	parts := strings.Split(strings.TrimPrefix(msg, "SEND_CONTACT:"), "|")
+	if len(parts) < 3 || (len(parts)-1)%2 != 0 {
+		b.Log.Errorf("Invalid SEND_CONTACT format")
+		return
+	}
+
+	jid, err := types.ParseJID(parts[0])
+	if err != nil {
+		b.Log.Errorf("JID parse error: %v", err)
+		return
+	}
+
+	contacts := make([]*waProto.ContactMessage, 0, (len(parts)-1)/2)
+	for i := 1; i+1 < len(parts); i += 2 {
+		name, phone := strings.TrimSpace(parts[i]), strings.TrimSpace(parts[i+1])
+		if name == "" || phoneDigits(phone) == "" {
+			b.Log.Errorf("Contact name and phone are required")
+			return
+		}
+		contacts = append(contacts, &waProto.ContactMessage{
+			DisplayName: proto.String(name),
+			Vcard:       proto.String(buildVCard(name, phone)),
+		})
+	}
+
+	var message *waProto.Message
+	if len(contacts) == 1 {
+		message = &waProto.Message{ContactMessage: contacts[0]}
+	} else {
+		message = &waProto.Message{
+			ContactsArrayMessage: &waProto.ContactsArrayMessage{
+				DisplayName: proto.String(fmt.Sprintf("%d contacts", len(contacts))),
+				Contacts:    contacts,
+			},
+		}
+	}
+
+	b.queueSend(jid, message, func(_ whatsmeow.SendResponse, err error) {
+		if err != nil {
+			b.Log.Errorf("Contact send error: %v", err)
+		}
+	})
+}
+
+func parseContacts(msg *waProto.Message) []map[string]interface{} {
+	var cards []*waProto.ContactMessage
+	if contact := msg.GetContactMessage(); contact != nil {
+		cards = append(cards, contact)
+	} else if array := msg.GetContactsArrayMessage(); array != nil {
+		cards = array.GetContacts()
+	}
+
+	if len(cards) == 0 {
+		return nil
+	}
+
+	contacts := make([]map[string]interface{}, 0, len(cards))
+	for _, card := range cards {
+		contacts = append(contacts, map[string]interface{}{
+			"displayName": card.GetDisplayName(),
+			"vcard":       card.GetVcard(),
+			"phones":      parseVCardPhones(card.GetVcard()),
+		})
+	}
+	return contacts
+}
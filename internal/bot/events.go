@@ -13,6 +13,10 @@ func (b *Bot) eventHandler(evt interface{}) {
 		b.onConnected(v)
 	case *events.Disconnected:
 		b.onDisconnected()
+	case *events.GroupInfo:
+		if len(v.Promote) > 0 || len(v.Demote) > 0 || len(v.Leave) > 0 {
+			b.perms.forgetGroup(v.JID)
+		}
 	case *events.HistorySync:
 		b.Log.Infof("History sync: %d conversations", len(v.Data.GetConversations()))
 	}
@@ -23,6 +27,11 @@ func (b *Bot) handleMessage(msg *events.Message) {
 		return
 	}
 
+	if msg.Message.GetPollUpdateMessage() != nil {
+		b.handlePollVote(msg)
+		return
+	}
+
 	text := ""
 	if conv := msg.Message.GetConversation(); conv != "" {
 		text = conv
@@ -30,6 +39,10 @@ func (b *Bot) handleMessage(msg *events.Message) {
 		text = ext.GetText()
 	}
 
+	if b.routeMessage(msg, text) {
+		return
+	}
+
 	isImage := msg.Message.GetImageMessage() != nil
 	var quotedMsg *waProto.Message
 	var isQuotedImage bool
@@ -54,6 +67,15 @@ func (b *Bot) handleMessage(msg *events.Message) {
 		"messageId":     msg.Info.ID,
 		"isImage":       isImage,
 		"isQuotedImage": isQuotedImage,
+		"mediaType":     messageMediaType(msg.Message),
+	}
+
+	if location := parseLocation(msg.Message); location != nil {
+		eventContent["location"] = location
+	}
+
+	if contacts := parseContacts(msg.Message); contacts != nil {
+		eventContent["contacts"] = contacts
 	}
 
 	if isQuotedImage {
@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Fetch error codes reported to the TS side alongside the reason.
+const (
+	FetchInvalidURL     = "invalid_url"
+	FetchBlockedAddress = "blocked_address"
+	FetchRequestFailed  = "request_failed"
+	FetchBadStatus      = "bad_status"
+	FetchTooLarge       = "too_large"
+	FetchWrongType      = "wrong_type"
+)
+
+// FetchError describes why a remote media URL was rejected.
+type FetchError struct {
+	URL    string
+	Code   string
+	Reason string
+	Err    error
+}
+
+func (e *FetchError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("fetch %s: %s: %v", e.URL, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("fetch %s: %s", e.URL, e.Reason)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Fetcher downloads media from user supplied URLs with bounded time, size and
+// redirects, and checks the payload is the kind of media that was asked for.
+type Fetcher struct {
+	client       *http.Client
+	guard        *addressGuard
+	MaxBytes     int64
+	MaxRedirects int
+}
+
+func NewFetcher(timeout time.Duration, maxBytes int64, maxRedirects int) *Fetcher {
+	f := &Fetcher{
+		guard:        newAddressGuardFromEnv(),
+		MaxBytes:     maxBytes,
+		MaxRedirects: maxRedirects,
+	}
+	f.client = &http.Client{
+		Timeout:       timeout,
+		CheckRedirect: f.checkRedirect,
+		Transport: &http.Transport{
+			// no proxy: the guard has to see the real destination address
+			Proxy:                 nil,
+			DialContext:           f.guard.dialContext(30 * time.Second),
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          20,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+		},
+	}
+	return f
+}
+
+func newFetcherFromEnv() *Fetcher {
+	return NewFetcher(
+		time.Duration(envInt("FETCH_TIMEOUT", 60))*time.Second,
+		int64(envInt("FETCH_MAX_BYTES", maxMediaSize)),
+		envInt("FETCH_MAX_REDIRECTS", 5),
+	)
+}
+
+func (f *Fetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > f.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", f.MaxRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	return f.guard.checkHost(req.URL.Hostname())
+}
+
+func validateFetchURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, &FetchError{URL: rawURL, Code: FetchInvalidURL, Reason: "invalid URL", Err: err}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, &FetchError{URL: rawURL, Code: FetchInvalidURL, Reason: "only http and https URLs are allowed"}
+	}
+	if u.Host == "" {
+		return nil, &FetchError{URL: rawURL, Code: FetchInvalidURL, Reason: "URL has no host"}
+	}
+	return u, nil
+}
+
+// Fetch downloads rawURL and returns its body and detected content type.
+// An empty mediaType skips the content type check.
+func (f *Fetcher) Fetch(rawURL string, mediaType MediaType) ([]byte, string, error) {
+	return f.FetchWithProgress(rawURL, mediaType, nil)
+}
+
+// FetchWithProgress is Fetch, reporting the body download to progress.
+func (f *Fetcher) FetchWithProgress(rawURL string, mediaType MediaType, progress *progressTracker) ([]byte, string, error) {
+	u, err := validateFetchURL(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, "", &FetchError{URL: rawURL, Code: FetchInvalidURL, Reason: "invalid request", Err: err}
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+
+	if err := f.guard.checkHost(u.Hostname()); err != nil {
+		return nil, "", &FetchError{URL: rawURL, Code: FetchBlockedAddress, Reason: "address not allowed", Err: err}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		var blocked *BlockedAddressError
+		if errors.As(err, &blocked) {
+			return nil, "", &FetchError{URL: rawURL, Code: FetchBlockedAddress, Reason: "address not allowed", Err: blocked}
+		}
+		return nil, "", &FetchError{URL: rawURL, Code: FetchRequestFailed, Reason: "request failed", Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", &FetchError{URL: rawURL, Code: FetchBadStatus, Reason: fmt.Sprintf("unexpected status code %d", resp.StatusCode)}
+	}
+	if f.MaxBytes > 0 && resp.ContentLength > f.MaxBytes {
+		return nil, "", &FetchError{URL: rawURL, Code: FetchTooLarge, Reason: fmt.Sprintf("file is %d bytes, limit is %d", resp.ContentLength, f.MaxBytes)}
+	}
+
+	progress.SetTotal(resp.ContentLength)
+	body := io.Reader(&progressReader{r: resp.Body, progress: progress})
+	if f.MaxBytes > 0 {
+		body = io.LimitReader(body, f.MaxBytes+1)
+	}
+	data, err := io.ReadAll(body)
+	progress.Finish()
+	if err != nil {
+		return nil, "", &FetchError{URL: rawURL, Code: FetchRequestFailed, Reason: "read failed", Err: err}
+	}
+	if f.MaxBytes > 0 && int64(len(data)) > f.MaxBytes {
+		return nil, "", &FetchError{URL: rawURL, Code: FetchTooLarge, Reason: fmt.Sprintf("file exceeds the %d byte limit", f.MaxBytes)}
+	}
+	if len(data) == 0 {
+		return nil, "", &FetchError{URL: rawURL, Code: FetchBadStatus, Reason: "empty response"}
+	}
+
+	sniffed := http.DetectContentType(data)
+	declared, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "" && !matchesMediaType(mediaType, sniffed) && !matchesMediaType(mediaType, declared) {
+		return nil, "", &FetchError{
+			URL:    rawURL,
+			Code:   FetchWrongType,
+			Reason: fmt.Sprintf("expected %s but got %s", mediaType, sniffed),
+		}
+	}
+
+	contentType := sniffed
+	if strings.HasPrefix(contentType, "application/octet-stream") && declared != "" {
+		contentType = declared
+	}
+	return data, contentType, nil
+}
+
+func matchesMediaType(mediaType MediaType, contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	switch mediaType {
+	case MediaImage:
+		return strings.HasPrefix(contentType, "image/")
+	case MediaVideo, MediaGIF:
+		return strings.HasPrefix(contentType, "video/")
+	case MediaAudio:
+		// m4a and ogg sniff as video/mp4 and application/ogg
+		return strings.HasPrefix(contentType, "audio/") ||
+			contentType == "video/mp4" ||
+			contentType == "application/ogg"
+	}
+	return false
+}
+
+// fetchErrorReason returns the user facing part of a fetch error.
+func fetchErrorReason(err error) string {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Reason
+	}
+	return err.Error()
+}
+
+// fetchErrorDetails describes err for an event payload, adding the error code
+// and, for blocked fetches, the host and address that were refused.
+func fetchErrorDetails(err error) map[string]interface{} {
+	details := map[string]interface{}{
+		"error": fetchErrorReason(err),
+	}
+
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		details["code"] = fetchErr.Code
+	}
+
+	var blocked *BlockedAddressError
+	if errors.As(err, &blocked) {
+		details["blocked"] = map[string]interface{}{
+			"host":   blocked.Host,
+			"ip":     blocked.IP,
+			"reason": blocked.Reason,
+		}
+	}
+	return details
+}
@@ -0,0 +1,300 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// Job states recorded in the jobs table.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+const jobsSchema = `
+CREATE TABLE IF NOT EXISTS awara_jobs (
+	id         TEXT    PRIMARY KEY,
+	kind       TEXT    NOT NULL,
+	chat       TEXT    NOT NULL,
+	sender     TEXT    NOT NULL,
+	command    TEXT    NOT NULL,
+	state      TEXT    NOT NULL,
+	error      TEXT    NOT NULL DEFAULT '',
+	attempts   INTEGER NOT NULL DEFAULT 0,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS awara_jobs_state ON awara_jobs (state, created_at);`
+
+var errJobInterrupted = errors.New("interrupted by a restart")
+
+// Commands longer than jobCommandMax, such as ENHANCE: or SEND_IMAGE: with a
+// base64 payload, are stored as a short preview ending in jobPayloadOmitted.
+// They can't be replayed, so a restart fails them instead.
+const (
+	jobCommandMax     = 4096
+	jobCommandPreview = 256
+	jobPayloadOmitted = "...[payload not stored]"
+)
+
+// storedCommand is the form of command kept in the jobs table.
+func storedCommand(command string) string {
+	if len(command) <= jobCommandMax {
+		return command
+	}
+	return strings.ToValidUTF8(command[:jobCommandPreview], "") + jobPayloadOmitted
+}
+
+// JobRecord is a persisted job as reported by the JOBS command.
+type JobRecord struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Chat      string `json:"chat"`
+	Sender    string `json:"sender"`
+	Command   string `json:"-"`
+	State     string `json:"state"`
+	Error     string `json:"error,omitempty"`
+	Attempts  int    `json:"attempts"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// jobStore keeps every scheduled job in sqlite so requests interrupted by a
+// restart can be resumed or answered with an error instead of hanging.
+type jobStore struct {
+	db *sql.DB
+}
+
+func newJobStore(db *sql.DB) (*jobStore, error) {
+	if _, err := db.Exec(jobsSchema); err != nil {
+		return nil, err
+	}
+
+	s := &jobStore{db: db}
+	retention := time.Duration(envInt("JOBS_RETENTION", 7*24*3600)) * time.Second
+	s.db.Exec(
+		`DELETE FROM awara_jobs WHERE state IN (?, ?) AND updated_at < ?`,
+		JobDone, JobFailed, time.Now().Add(-retention).Unix(),
+	)
+	return s, nil
+}
+
+// Queue records a job as queued. Resubmitting an existing ID keeps its attempt
+// count and creation time.
+func (s *jobStore) Queue(j *job) error {
+	now := time.Now().Unix()
+	_, err := s.db.Exec(`
+		INSERT INTO awara_jobs (id, kind, chat, sender, command, state, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET state = excluded.state, error = '', updated_at = excluded.updated_at`,
+		j.id, string(j.kind), j.chat, j.sender, storedCommand(j.command), JobQueued, now, now,
+	)
+	return err
+}
+
+func (s *jobStore) Start(id string) error {
+	_, err := s.db.Exec(
+		`UPDATE awara_jobs SET state = ?, attempts = attempts + 1, updated_at = ? WHERE id = ?`,
+		JobRunning, time.Now().Unix(), id,
+	)
+	return err
+}
+
+func (s *jobStore) Finish(id string, jobErr error) error {
+	state, message := JobDone, ""
+	if jobErr != nil {
+		state, message = JobFailed, jobErr.Error()
+	}
+
+	_, err := s.db.Exec(
+		`UPDATE awara_jobs SET state = ?, error = ?, updated_at = ? WHERE id = ?`,
+		state, message, time.Now().Unix(), id,
+	)
+	return err
+}
+
+// Unfinished returns jobs left queued or running by a previous process, oldest first.
+func (s *jobStore) Unfinished() ([]JobRecord, error) {
+	return s.query(`WHERE state IN (?, ?) ORDER BY created_at`, JobQueued, JobRunning)
+}
+
+// List returns the most recent jobs, optionally filtered by chat and state.
+func (s *jobStore) List(chat, state string, limit int) ([]JobRecord, error) {
+	var where []string
+	var args []interface{}
+	if chat != "" {
+		where = append(where, "chat = ?")
+		args = append(args, chat)
+	}
+	if state != "" {
+		where = append(where, "state = ?")
+		args = append(args, state)
+	}
+
+	clause := ""
+	if len(where) > 0 {
+		clause = "WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, limit)
+	return s.query(clause+" ORDER BY created_at DESC LIMIT ?", args...)
+}
+
+func (s *jobStore) query(clause string, args ...interface{}) ([]JobRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT id, kind, chat, sender, command, state, error, attempts, created_at, updated_at
+		FROM awara_jobs `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("job query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var records []JobRecord
+	for rows.Next() {
+		var r JobRecord
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Chat, &r.Sender, &r.Command, &r.State, &r.Error, &r.Attempts, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// resumeJobs replays jobs a previous process left queued or running. Recent
+// jobs are submitted again under their original request ID; stale ones, or
+// ones that keep getting interrupted, are failed with a notice to the chat.
+func (b *Bot) resumeJobs() {
+	records, err := b.jobs.store.Unfinished()
+	if err != nil {
+		b.Log.Errorf("Failed to load unfinished jobs: %v", err)
+		return
+	}
+
+	window := int64(envInt("JOBS_RESUME_WINDOW", 900))
+	maxAttempts := envInt("JOBS_MAX_ATTEMPTS", 3)
+	for _, r := range records {
+		// a broadcast, scheduled send or batch download may have reached part
+		// of its targets; replaying it would message them twice
+		kind := JobKind(r.Kind)
+		partial := kind == JobBroadcast || kind == JobScheduled || strings.HasPrefix(r.Command, "BATCH_DOWNLOAD:")
+		resumable := (!partial || r.State == JobQueued) && !strings.HasSuffix(r.Command, jobPayloadOmitted)
+		if resumable && time.Now().Unix()-r.CreatedAt <= window && r.Attempts < maxAttempts {
+			b.Log.Infof("Resuming %s job %s", r.Kind, r.ID)
+			if strings.HasPrefix(r.Command, "SCHEDULE_RUN:") {
+				b.handleScheduleRun(r.Command)
+			} else {
+				b.processMessage(fmt.Sprintf("REQ:%s|%s|%s|%s", r.ID, r.Chat, r.Sender, r.Command))
+			}
+			continue
+		}
+		b.failInterruptedJob(r)
+	}
+}
+
+func (b *Bot) failInterruptedJob(r JobRecord) {
+	b.Log.Warnf("Failing interrupted %s job %s", r.Kind, r.ID)
+	if err := b.jobs.store.Finish(r.ID, errJobInterrupted); err != nil {
+		b.Log.Warnf("Failed to record job %s result: %v", r.ID, err)
+	}
+
+	// Answer on the channel the caller was waiting on.
+	switch kind := JobKind(r.Kind); {
+	case kind == JobDownload && strings.HasPrefix(r.Command, "SEARCH:"):
+		b.writeResult("SEARCH_RESULT", map[string]interface{}{
+			"type":      "search_result",
+			"requestId": r.ID,
+			"status":    false,
+			"error":     errJobInterrupted.Error(),
+		})
+	case kind == JobDownload && strings.HasPrefix(r.Command, "BATCH_DOWNLOAD:"):
+		b.sendBatchError(r.ID, r.Chat, errJobInterrupted)
+	case kind == JobDownload && strings.HasPrefix(r.Command, "FORMATS:"):
+		b.writeResult("FORMATS_RESULT", map[string]interface{}{
+			"type":      "formats_result",
+			"requestId": r.ID,
+			"status":    false,
+			"error":     errJobInterrupted.Error(),
+		})
+	case kind == JobDownload, kind == JobEnhance:
+		b.sendErrorResponse(r.ID, errJobInterrupted)
+	case kind == JobMedia && strings.HasPrefix(r.Command, "DOWNLOAD_MEDIA:"):
+		writeMediaData(r.ID, "error")
+	case kind == JobBroadcast:
+		chat, _ := types.ParseJID(r.Chat)
+		b.sendBroadcastError(requestMeta{ID: r.ID, Chat: chat}, errJobInterrupted)
+	case kind == JobScheduled:
+		b.sendEvent(BotEvent{
+			Type: "schedule_failed",
+			Content: map[string]interface{}{
+				"requestId": r.ID,
+				"chat":      r.Chat,
+				"status":    false,
+				"error":     errJobInterrupted.Error(),
+			},
+		})
+	}
+
+	b.sendEvent(BotEvent{
+		Type: "job_failed",
+		Content: map[string]interface{}{
+			"requestId": r.ID,
+			"kind":      r.Kind,
+			"chat":      r.Chat,
+			"error":     errJobInterrupted.Error(),
+		},
+	})
+
+	// nobody in a scheduled message's chat asked for it, and the TS side
+	// already tells the chat about a failed batch
+	chat, err := types.ParseJID(r.Chat)
+	if err != nil || chat.IsEmpty() || JobKind(r.Kind) == JobScheduled || strings.HasPrefix(r.Command, "BATCH_DOWNLOAD:") {
+		return
+	}
+	_, err = b.send(chat, &waProto.Message{
+		Conversation: proto.String(fmt.Sprintf("⚠️ Your %s request was interrupted by a restart, please try again.", r.Kind)),
+	})
+	if err != nil {
+		b.Log.Errorf("Failed to send job notice: %v", err)
+	}
+}
+
+// handleJobsQuery expects JOBS:[<chat>][|<state>[|<limit>]]
+func (b *Bot) handleJobsQuery(msg string) {
+	parts := strings.Split(strings.TrimPrefix(msg, "JOBS:"), "|")
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+
+	limit, err := strconv.Atoi(parts[2])
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	records, err := b.jobs.store.List(parts[0], parts[1], limit)
+	if err != nil {
+		b.writeResult("JOBS_RESULT", map[string]interface{}{
+			"type":   "jobs_result",
+			"status": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	if records == nil {
+		records = []JobRecord{}
+	}
+	b.writeResult("JOBS_RESULT", map[string]interface{}{
+		"type":   "jobs_result",
+		"status": true,
+		"jobs":   records,
+	})
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// handleSendLocation expects SEND_LOCATION:<jid>|<lat>|<long>|<name>|<address>
+func (b *Bot) handleSendLocation(msg string) {
+	parts := strings.SplitN(strings.TrimPrefix(msg, "SEND_LOCATION:"), "|", 5)
+	if len(parts) < 3 {
+		b.Log.Errorf("Invalid SEND_LOCATION format")
+		return
+	}
+
+	jid, err := types.ParseJID(parts[0])
+	if err != nil {
+		b.Log.Errorf("JID parse error: %v", err)
+		return
+	}
+
+	lat, ok := parseCoordinate(parts[1], 90)
+	if !ok {
+		b.Log.Errorf("Invalid latitude: %s", parts[1])
+		return
+	}
+
+	long, ok := parseCoordinate(parts[2], 180)
+	if !ok {
+		b.Log.Errorf("Invalid longitude: %s", parts[2])
+		return
+	}
+
+	location := &waProto.LocationMessage{
+		DegreesLatitude:  proto.Float64(lat),
+		DegreesLongitude: proto.Float64(long),
+	}
+	if len(parts) > 3 && parts[3] != "" {
+		location.Name = proto.String(parts[3])
+	}
+	if len(parts) > 4 && parts[4] != "" {
+		location.Address = proto.String(strings.ReplaceAll(parts[4], "{{NL}}", "\n"))
+	}
+
+	b.queueSend(jid, &waProto.Message{
+		LocationMessage: location,
+	}, func(_ whatsmeow.SendResponse, err error) {
+		if err != nil {
+			b.Log.Errorf("Location send error: %v", err)
+		}
+	})
+}
+
+// parseCoordinate parses a degree value within [-limit, limit]. ParseFloat
+// accepts "NaN" and "Inf", which no range check would catch on its own.
+func parseCoordinate(value string, limit float64) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < -limit || v > limit {
+		return 0, false
+	}
+	return v, true
+}
+
+func parseLocation(msg *waProto.Message) map[string]interface{} {
+	if loc := msg.GetLocationMessage(); loc != nil {
+		return map[string]interface{}{
+			"latitude":  loc.GetDegreesLatitude(),
+			"longitude": loc.GetDegreesLongitude(),
+			"name":      loc.GetName(),
+			"address":   loc.GetAddress(),
+			"url":       loc.GetURL(),
+			"comment":   loc.GetComment(),
+			"isLive":    loc.GetIsLive(),
+		}
+	}
+
+	if live := msg.GetLiveLocationMessage(); live != nil {
+		return map[string]interface{}{
+			"latitude":  live.GetDegreesLatitude(),
+			"longitude": live.GetDegreesLongitude(),
+			"accuracy":  live.GetAccuracyInMeters(),
+			"speed":     live.GetSpeedInMps(),
+			"heading":   live.GetDegreesClockwiseFromMagneticNorth(),
+			"caption":   live.GetCaption(),
+			"sequence":  live.GetSequenceNumber(),
+			"isLive":    true,
+		}
+	}
+
+	return nil
+}
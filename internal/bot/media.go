@@ -1,10 +1,8 @@
 package bot
 
 import (
-	"context"
 	"encoding/base64"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"os/exec"
@@ -17,14 +15,41 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// maxMediaSize is the largest file WhatsApp accepts for image, video and audio messages.
+const maxMediaSize = 100 << 20
+
 type MediaType string
 
 const (
 	MediaImage MediaType = "image"
 	MediaVideo MediaType = "video"
 	MediaAudio MediaType = "audio"
+	// MediaGIF is an MP4 sent as a looping, muted GIF.
+	MediaGIF MediaType = "gif"
+
+	MediaLocation     MediaType = "location"
+	MediaLiveLocation MediaType = "live_location"
+	MediaContact      MediaType = "contact"
 )
 
+func messageMediaType(msg *waProto.Message) MediaType {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return MediaImage
+	case msg.GetVideoMessage() != nil:
+		return MediaVideo
+	case msg.GetAudioMessage() != nil:
+		return MediaAudio
+	case msg.GetLocationMessage() != nil:
+		return MediaLocation
+	case msg.GetLiveLocationMessage() != nil:
+		return MediaLiveLocation
+	case msg.GetContactMessage() != nil, msg.GetContactsArrayMessage() != nil:
+		return MediaContact
+	}
+	return ""
+}
+
 func getAudioDuration(path string) (float64, error) {
 	cmd := exec.Command("ffprobe",
 		"-v", "error",
@@ -58,37 +83,56 @@ func (b *Bot) getAudioDuration(audioData []byte) (float64, error) {
 	return getAudioDuration(tmpFile.Name())
 }
 
-func (b *Bot) uploadAndSendMedia(jid types.JID, mediaData []byte, mediaType MediaType, caption string) error {
-	var waMediaType whatsmeow.MediaType
+func (b *Bot) uploadAndSendMedia(jid types.JID, mediaData []byte, mediaType MediaType, caption string, progress *progressTracker) error {
+	msg, err := b.uploadMedia(mediaData, mediaType, caption, progress)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.send(jid, msg)
+	return err
+}
+
+func waMediaType(mediaType MediaType) whatsmeow.MediaType {
+	switch mediaType {
+	case MediaVideo, MediaGIF:
+		return whatsmeow.MediaVideo
+	case MediaAudio:
+		return whatsmeow.MediaAudio
+	}
+	return whatsmeow.MediaImage
+}
+
+// uploadMedia uploads mediaData and returns the message that references it, ready to be sent.
+// progress may be nil.
+func (b *Bot) uploadMedia(mediaData []byte, mediaType MediaType, caption string, progress *progressTracker) (*waProto.Message, error) {
 	var msg *waProto.Message
 
 	switch mediaType {
 	case MediaImage:
-		waMediaType = whatsmeow.MediaImage
 		msg = &waProto.Message{
 			ImageMessage: &waProto.ImageMessage{
 				Caption: proto.String(caption),
 			},
 		}
-	case MediaVideo:
-		waMediaType = whatsmeow.MediaVideo
+	case MediaVideo, MediaGIF:
 		msg = &waProto.Message{
 			VideoMessage: &waProto.VideoMessage{
-				Caption: proto.String(caption),
+				Caption:     proto.String(caption),
+				GifPlayback: proto.Bool(mediaType == MediaGIF),
 			},
 		}
 	case MediaAudio:
-		waMediaType = whatsmeow.MediaAudio
 		msg = &waProto.Message{
 			AudioMessage: &waProto.AudioMessage{},
 		}
 	default:
-		return fmt.Errorf("unsupported media type")
+		return nil, fmt.Errorf("unsupported media type")
 	}
 
-	uploaded, err := b.Client.Upload(context.Background(), mediaData, waMediaType)
+	uploaded, err := b.upload(mediaData, waMediaType(mediaType), progress)
 	if err != nil {
-		return fmt.Errorf("upload failed: %v", err)
+		return nil, err
 	}
 
 	switch mediaType {
@@ -101,7 +145,7 @@ func (b *Bot) uploadAndSendMedia(jid types.JID, mediaData []byte, mediaType Medi
 		imgMsg.FileEncSHA256 = uploaded.FileEncSHA256
 		imgMsg.FileSHA256 = uploaded.FileSHA256
 		imgMsg.FileLength = proto.Uint64(uint64(len(mediaData)))
-	case MediaVideo:
+	case MediaVideo, MediaGIF:
 		vidMsg := msg.VideoMessage
 		vidMsg.Mimetype = proto.String(http.DetectContentType(mediaData))
 		vidMsg.URL = proto.String(uploaded.URL)
@@ -125,11 +169,10 @@ func (b *Bot) uploadAndSendMedia(jid types.JID, mediaData []byte, mediaType Medi
 		}
 	}
 
-	_, err = b.Client.SendMessage(context.Background(), jid, msg)
-	return err
+	return msg, nil
 }
 
-func (b *Bot) processMediaCommand(msg string, prefix string, mediaType MediaType) {
+func (b *Bot) processMediaCommand(meta requestMeta, msg string, prefix string, mediaType MediaType) {
 	content := strings.SplitN(strings.TrimPrefix(msg, prefix), "|", 3)
 	if len(content) < 2 {
 		b.Log.Errorf("Invalid %s message format", mediaType)
@@ -141,46 +184,55 @@ func (b *Bot) processMediaCommand(msg string, prefix string, mediaType MediaType
 		b.Log.Errorf("JID parse error: %v", err)
 		return
 	}
+	if meta.Chat.IsEmpty() {
+		meta.Chat = jid
+	}
 
-	var mediaData []byte
-	var caption string
-
+	var url, caption string
 	if strings.HasPrefix(prefix, "SEND_URL_") {
-		url := content[1]
-		if !strings.HasPrefix(url, "http") {
-			b.Log.Errorf("Invalid URL scheme: %s", url)
-			return
-		}
-
-		resp, err := http.Get(url)
-		if err != nil {
-			b.Log.Errorf("Failed to download %s: %v", mediaType, err)
-			return
-		}
-		defer resp.Body.Close()
-
-		mediaData, err = io.ReadAll(resp.Body)
-		if err != nil {
-			b.Log.Errorf("Failed to read %s data: %v", mediaType, err)
-			return
-		}
+		url = content[1]
+	}
+	if len(content) > 2 {
+		caption = strings.ReplaceAll(content[2], "{{NL}}", "\n")
+	}
 
-		if len(content) > 2 {
-			caption = strings.ReplaceAll(content[2], "{{NL}}", "\n")
-		}
-	} else {
+	// downloading, uploading and the throttled send all happen on a media
+	// worker so the stdin reader can move on to the next command
+	b.jobs.Submit(JobMedia, meta, msg, func() error {
+		var mediaData []byte
 		var err error
-		mediaData, err = base64.StdEncoding.DecodeString(content[1])
-		if err != nil {
-			return
+		if url != "" {
+			mediaData, _, err = b.fetcher.FetchWithProgress(url, mediaType, b.newProgress(meta, "download"))
+			if err != nil {
+				b.Log.Errorf("Failed to download %s: %v", mediaType, err)
+				b.sendMediaError(jid, mediaType, url, err)
+				return err
+			}
+		} else if mediaData, err = base64.StdEncoding.DecodeString(content[1]); err != nil {
+			b.Log.Errorf("Invalid %s data: %v", mediaType, err)
+			b.sendMediaError(jid, mediaType, "", err)
+			return err
 		}
 
-		if len(content) > 2 {
-			caption = strings.ReplaceAll(content[2], "{{NL}}", "\n")
+		if err := b.uploadAndSendMedia(jid, mediaData, mediaType, caption, b.newProgress(meta, "upload")); err != nil {
+			b.Log.Errorf("%s send error: %v", strings.Title(string(mediaType)), err)
+			b.sendMediaError(jid, mediaType, url, err)
+			return err
 		}
-	}
+		return nil
+	}, func(err error) {
+		b.sendMediaError(jid, mediaType, url, err)
+	})
+}
 
-	if err := b.uploadAndSendMedia(jid, mediaData, mediaType, caption); err != nil {
-		b.Log.Errorf("%s send error: %v", strings.Title(string(mediaType)), err)
-	}
+func (b *Bot) sendMediaError(jid types.JID, mediaType MediaType, url string, err error) {
+	content := fetchErrorDetails(err)
+	content["chat"] = jid.String()
+	content["mediaType"] = mediaType
+	content["url"] = url
+
+	b.sendEvent(BotEvent{
+		Type:    "media_error",
+		Content: content,
+	})
 }
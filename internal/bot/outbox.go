@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+)
+
+type sendResult struct {
+	resp whatsmeow.SendResponse
+	err  error
+}
+
+type outboundMessage struct {
+	jid    types.JID
+	msg    *waProto.Message
+	result chan sendResult
+}
+
+// outbox sits in front of Client.SendMessage. Every chat has its own FIFO so
+// messages to one chat keep their order, while a global and a per-recipient
+// token bucket plus a randomized delay keep the send rate looking human.
+type outbox struct {
+	bot *Bot
+
+	mu      sync.Mutex
+	queues  map[string][]*outboundMessage
+	global  *tokenLimiter
+	perChat *tokenLimiter
+
+	minDelay time.Duration
+	maxDelay time.Duration
+	typing   bool
+}
+
+func newOutboxFromEnv(b *Bot) *outbox {
+	o := &outbox{
+		bot:      b,
+		queues:   make(map[string][]*outboundMessage),
+		global:   newTokenLimiter(envInt("SEND_GLOBAL_BURST", 20), envInt("SEND_GLOBAL_PER_MINUTE", 60)),
+		perChat:  newTokenLimiter(envInt("SEND_CHAT_BURST", 5), envInt("SEND_CHAT_PER_MINUTE", 20)),
+		minDelay: time.Duration(envInt("SEND_MIN_DELAY_MS", 300)) * time.Millisecond,
+		maxDelay: time.Duration(envInt("SEND_MAX_DELAY_MS", 1500)) * time.Millisecond,
+		typing:   os.Getenv("SEND_TYPING") != "off",
+	}
+
+	go func() {
+		for range time.Tick(10 * time.Minute) {
+			o.mu.Lock()
+			o.perChat.prune(time.Now())
+			o.mu.Unlock()
+		}
+	}()
+	return o
+}
+
+// Enqueue adds msg to jid's queue. The position is fixed when Enqueue returns,
+// so callers that enqueue in order are delivered in order.
+func (o *outbox) Enqueue(jid types.JID, msg *waProto.Message) <-chan sendResult {
+	m := &outboundMessage{jid: jid, msg: msg, result: make(chan sendResult, 1)}
+	key := jid.String()
+
+	o.mu.Lock()
+	queue := o.queues[key]
+	o.queues[key] = append(queue, m)
+	if len(queue) == 0 {
+		go o.drain(key)
+	}
+	o.mu.Unlock()
+
+	return m.result
+}
+
+// drain delivers key's queue one message at a time. The head stays in the
+// queue until it is sent so Enqueue doesn't start a second drain.
+func (o *outbox) drain(key string) {
+	o.mu.Lock()
+	m := o.queues[key][0]
+	o.mu.Unlock()
+
+	for {
+		o.deliver(key, m)
+
+		o.mu.Lock()
+		queue := o.queues[key][1:]
+		if len(queue) == 0 {
+			delete(o.queues, key)
+			o.mu.Unlock()
+			return
+		}
+		o.queues[key] = queue
+		m = queue[0]
+		o.mu.Unlock()
+	}
+}
+
+func (o *outbox) deliver(key string, m *outboundMessage) {
+	o.waitForBudget(key)
+
+	delay := o.minDelay
+	if o.maxDelay > o.minDelay {
+		delay += time.Duration(rand.Int63n(int64(o.maxDelay - o.minDelay)))
+	}
+
+	isText := m.msg.GetConversation() != "" || m.msg.GetExtendedTextMessage() != nil
+	if o.typing && isText {
+		// a typing indicator that lasts roughly as long as the message took to
+		// "write", capped so long replies aren't held back
+		typingTime := min(time.Duration(len(m.msg.GetConversation()))*20*time.Millisecond, 4*time.Second)
+		o.bot.Client.SendChatPresence(m.jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+		time.Sleep(delay + typingTime)
+		o.bot.Client.SendChatPresence(m.jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
+	} else {
+		time.Sleep(delay)
+	}
+
+	resp, err := o.bot.Client.SendMessage(context.Background(), m.jid, m.msg)
+	m.result <- sendResult{resp: resp, err: err}
+}
+
+// waitForBudget blocks until both the global and key's bucket have a token.
+func (o *outbox) waitForBudget(key string) {
+	for {
+		o.mu.Lock()
+		now := time.Now()
+		wait := max(o.global.wait("", 1, now), o.perChat.wait(key, 1, now))
+		if wait == 0 {
+			o.global.take("", 1)
+			o.perChat.take(key, 1)
+			o.mu.Unlock()
+			return
+		}
+		o.mu.Unlock()
+		time.Sleep(wait)
+	}
+}
+
+// send queues msg for jid and waits until it has been delivered.
+func (b *Bot) send(jid types.JID, msg *waProto.Message) (whatsmeow.SendResponse, error) {
+	r := <-b.outbox.Enqueue(jid, msg)
+	return r.resp, r.err
+}
+
+// queueSend queues msg without waiting; done, if not nil, runs once it has
+// been delivered. Used by stdin handlers so a throttled chat doesn't block
+// reading the next command.
+func (b *Bot) queueSend(jid types.JID, msg *waProto.Message, done func(whatsmeow.SendResponse, error)) {
+	result := b.outbox.Enqueue(jid, msg)
+	go func() {
+		r := <-result
+		if done != nil {
+			done(r.resp, r.err)
+		}
+	}()
+}
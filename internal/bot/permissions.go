@@ -0,0 +1,474 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// Built-in roles. Owners come from OWNER_NUMBERS and hold every role; admin is
+// also held by the admins of a group while they are in that group. Any other
+// role name is a custom role granted with ROLE: or the role command.
+const (
+	RoleOwner = "owner"
+	RoleAdmin = "admin"
+)
+
+var roleNameRe = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+const rolesSchema = `
+CREATE TABLE IF NOT EXISTS awara_roles (
+	jid        TEXT    NOT NULL,
+	chat       TEXT    NOT NULL DEFAULT '',
+	role       TEXT    NOT NULL,
+	granted_by TEXT    NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL,
+	PRIMARY KEY (jid, chat, role)
+);`
+
+// RoleGrant is a custom role held by a user, in one chat or everywhere (empty Chat).
+type RoleGrant struct {
+	JID       string `json:"jid"`
+	Chat      string `json:"chat,omitempty"`
+	Role      string `json:"role"`
+	GrantedBy string `json:"grantedBy,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+type roleStore struct {
+	db *sql.DB
+}
+
+func newRoleStore(db *sql.DB) (*roleStore, error) {
+	if _, err := db.Exec(rolesSchema); err != nil {
+		return nil, err
+	}
+	return &roleStore{db: db}, nil
+}
+
+func (s *roleStore) Grant(jid, chat, role, grantedBy string) error {
+	_, err := s.db.Exec(
+		`INSERT OR IGNORE INTO awara_roles (jid, chat, role, granted_by, created_at) VALUES (?, ?, ?, ?, ?)`,
+		jid, chat, role, grantedBy, time.Now().Unix(),
+	)
+	return err
+}
+
+func (s *roleStore) Revoke(jid, chat, role string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM awara_roles WHERE jid = ? AND chat = ? AND role = ?`, jid, chat, role)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Has reports whether jid holds role everywhere or in chat.
+func (s *roleStore) Has(jid, chat, role string) (bool, error) {
+	var n int
+	err := s.db.QueryRow(
+		`SELECT COUNT(*) FROM awara_roles WHERE jid = ? AND role = ? AND (chat = '' OR chat = ?)`,
+		jid, role, chat,
+	).Scan(&n)
+	return n > 0, err
+}
+
+func (s *roleStore) List(jid string) ([]RoleGrant, error) {
+	query := `SELECT jid, chat, role, granted_by, created_at FROM awara_roles`
+	var args []interface{}
+	if jid != "" {
+		query += ` WHERE jid = ?`
+		args = append(args, jid)
+	}
+
+	rows, err := s.db.Query(query+` ORDER BY jid, chat, role`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []RoleGrant{}
+	for rows.Next() {
+		var g RoleGrant
+		if err := rows.Scan(&g.JID, &g.Chat, &g.Role, &g.GrantedBy, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// ipcRule is the role an IPC op needs when it is sent on behalf of a chat
+// user, and how to answer the caller when it is refused.
+type ipcRule struct {
+	role string
+	deny func(b *Bot, meta requestMeta, err error)
+}
+
+func denyWithResult(prefix, resultType string) func(*Bot, requestMeta, error) {
+	return func(b *Bot, meta requestMeta, err error) {
+		b.writeResult(prefix, map[string]interface{}{
+			"type":      resultType,
+			"requestId": meta.ID,
+			"status":    false,
+			"error":     err.Error(),
+		})
+	}
+}
+
+var defaultIPCRules = map[string]ipcRule{
+	"BROADCAST": {RoleOwner, func(b *Bot, meta requestMeta, err error) { b.sendBroadcastError(meta, err) }},
+	"TAG":       {RoleOwner, denyWithResult("TAG_RESULT", "tag_result")},
+	"ROLE":      {RoleOwner, denyWithResult("ROLE_RESULT", "role_result")},
+	"PLUGINS":   {RoleOwner, denyWithResult("PLUGINS_RESULT", "plugins_result")},
+	"SCHEDULE":  {RoleAdmin, denyWithResult("SCHEDULE_RESULT", "schedule_result")},
+	"JOBS":      {RoleAdmin, denyWithResult("JOBS_RESULT", "jobs_result")},
+}
+
+// ipcRuleOps lists the IPC ops that have a rule, sorted.
+func ipcRuleOps() []string {
+	ops := make([]string, 0, len(defaultIPCRules))
+	for op := range defaultIPCRules {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// PermissionError is returned when the sender lacks the role a command or IPC op needs.
+type PermissionError struct {
+	Name string
+	Role string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("you need the %s role to use %s", e.Role, e.Name)
+}
+
+// groupMembers is the cached part of a group's participant list: who its
+// admins are (by phone number and LID user) and the phone JID behind each LID.
+type groupMembers struct {
+	admins  map[string]bool
+	phones  map[string]types.JID
+	fetched time.Time
+}
+
+// permissions resolves who holds which role.
+type permissions struct {
+	roles        *roleStore
+	adminTTL     time.Duration
+	commandRoles map[string]string
+	ipcRules     map[string]ipcRule
+
+	mu     sync.Mutex
+	groups map[types.JID]groupMembers
+}
+
+func newPermissionsFromEnv(roles *roleStore, log waLog.Logger) *permissions {
+	p := &permissions{
+		roles:        roles,
+		adminTTL:     time.Duration(envInt("GROUP_ADMIN_TTL", 300)) * time.Second,
+		commandRoles: parseRoleOverrides(os.Getenv("COMMAND_ROLES"), strings.ToLower),
+		ipcRules:     make(map[string]ipcRule, len(defaultIPCRules)),
+		groups:       make(map[types.JID]groupMembers),
+	}
+
+	for op, rule := range defaultIPCRules {
+		p.ipcRules[op] = rule
+	}
+	for op, role := range parseRoleOverrides(os.Getenv("IPC_ROLES"), strings.ToUpper) {
+		// a refused op has to answer its caller, which only the ops with a
+		// default rule know how to do; the TS side would wait for a timeout
+		rule, ok := p.ipcRules[op]
+		if !ok {
+			log.Warnf("Ignoring IPC_ROLES entry for %s: only %s can be restricted", op, strings.Join(ipcRuleOps(), ", "))
+			continue
+		}
+		rule.role = role
+		p.ipcRules[op] = rule
+	}
+	return p
+}
+
+// parseRoleOverrides reads name=role pairs separated by commas. A role of
+// "everyone" lifts the requirement.
+func parseRoleOverrides(value string, normalize func(string) string) map[string]string {
+	overrides := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			continue
+		}
+		role = strings.ToLower(strings.TrimSpace(role))
+		if role == "everyone" {
+			role = ""
+		}
+		overrides[normalize(strings.TrimSpace(name))] = role
+	}
+	return overrides
+}
+
+// commandRole is the role needed to run a command, after COMMAND_ROLES.
+func (p *permissions) commandRole(info CommandInfo) string {
+	if role, ok := p.commandRoles[strings.ToLower(info.Name)]; ok {
+		return role
+	}
+	return info.Role
+}
+
+func (p *permissions) forgetGroup(chat types.JID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.groups, chat)
+}
+
+// isOwner reports whether jid's number is listed in OWNER_NUMBERS. LID senders
+// have to be resolved with phoneJID first.
+func (b *Bot) isOwner(jid types.JID) bool {
+	if jid.User == "" {
+		return false
+	}
+	for _, number := range strings.Split(os.Getenv("OWNER_NUMBERS"), ",") {
+		if strings.TrimSpace(number) == jid.User {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRole reports whether sender holds role in chat. An empty role is held by everyone.
+func (b *Bot) hasRole(sender, chat types.JID, role string) bool {
+	if role == "" {
+		return true
+	}
+	sender = b.phoneJID(chat, sender)
+	if b.isOwner(sender) {
+		return true
+	}
+	if role == RoleOwner || sender.IsEmpty() {
+		return false
+	}
+
+	ok, err := b.perms.roles.Has(sender.ToNonAD().String(), chat.String(), role)
+	if err != nil {
+		b.Log.Errorf("Role lookup failed: %v", err)
+	}
+	if ok {
+		return true
+	}
+	return role == RoleAdmin && chat.Server == types.GroupServer && b.isGroupAdmin(chat, sender)
+}
+
+// groupMembers returns chat's participant info, cached for GROUP_ADMIN_TTL or
+// until a group change event arrives.
+func (b *Bot) groupMembers(chat types.JID) (groupMembers, bool) {
+	p := b.perms
+	p.mu.Lock()
+	members, ok := p.groups[chat]
+	p.mu.Unlock()
+
+	if ok && time.Since(members.fetched) <= p.adminTTL {
+		return members, true
+	}
+
+	info, err := b.Client.GetGroupInfo(chat)
+	if err != nil {
+		b.Log.Errorf("Failed to load participants of %s: %v", chat, err)
+		return groupMembers{}, false
+	}
+
+	members = groupMembers{admins: make(map[string]bool), phones: make(map[string]types.JID), fetched: time.Now()}
+	for _, participant := range info.Participants {
+		if !participant.LID.IsEmpty() {
+			members.phones[participant.LID.User] = participant.JID.ToNonAD()
+		}
+		if participant.IsAdmin || participant.IsSuperAdmin {
+			members.admins[participant.JID.User] = true
+			if !participant.LID.IsEmpty() {
+				members.admins[participant.LID.User] = true
+			}
+		}
+	}
+
+	p.mu.Lock()
+	p.groups[chat] = members
+	p.mu.Unlock()
+	return members, true
+}
+
+// isGroupAdmin checks sender against the group's admins.
+func (b *Bot) isGroupAdmin(chat, sender types.JID) bool {
+	members, ok := b.groupMembers(chat)
+	return ok && members.admins[sender.User]
+}
+
+// phoneJID maps an @lid sender in a LID-addressed group to their phone number
+// JID, which is what OWNER_NUMBERS and role grants are keyed by. Other JIDs,
+// and LIDs that can't be resolved, are returned unchanged.
+func (b *Bot) phoneJID(chat, sender types.JID) types.JID {
+	if sender.Server != types.HiddenUserServer || chat.Server != types.GroupServer {
+		return sender
+	}
+	if members, ok := b.groupMembers(chat); ok {
+		if phone, ok := members.phones[sender.User]; ok && !phone.IsEmpty() {
+			return phone
+		}
+	}
+	return sender
+}
+
+// sendPermissionDenied reports a refused command or IPC op.
+func (b *Bot) sendPermissionDenied(meta requestMeta, kind string, err *PermissionError) {
+	b.Log.Warnf("Denied %s %s to %s in %s (needs %s)", kind, err.Name, meta.Sender, meta.Chat, err.Role)
+	b.sendEvent(BotEvent{
+		Type: "permission_denied",
+		Content: map[string]interface{}{
+			"requestId": meta.ID,
+			"kind":      kind,
+			"name":      err.Name,
+			"role":      err.Role,
+			"sender":    meta.Sender.String(),
+			"chat":      meta.Chat.String(),
+		},
+	})
+}
+
+// checkIPCPermission refuses an IPC op sent on behalf of a user who lacks the
+// role it needs. Commands without a sender come from the host process itself
+// and are trusted.
+func (b *Bot) checkIPCPermission(meta requestMeta, msg string) bool {
+	if meta.Sender.IsEmpty() {
+		return true
+	}
+
+	op, _, _ := strings.Cut(msg, ":")
+	rule, ok := b.perms.ipcRules[op]
+	if !ok || b.hasRole(meta.Sender, meta.Chat, rule.role) {
+		return true
+	}
+
+	err := &PermissionError{Name: op, Role: rule.role}
+	b.sendPermissionDenied(meta, "ipc", err)
+	rule.deny(b, meta, err)
+	return false
+}
+
+// parseUserJID accepts a JID, a phone number or an @mention.
+func parseUserJID(value string) (types.JID, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "@")
+	if strings.Contains(value, "@") {
+		jid, err := types.ParseJID(value)
+		return jid.ToNonAD(), err
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+	if digits == "" {
+		return types.JID{}, fmt.Errorf("invalid user %q", value)
+	}
+	return types.NewJID(digits, types.DefaultUserServer), nil
+}
+
+func validateRoleName(role string) error {
+	if role == RoleOwner {
+		return errors.New("owners are set with OWNER_NUMBERS")
+	}
+	if !roleNameRe.MatchString(role) {
+		return fmt.Errorf("invalid role name %q", role)
+	}
+	return nil
+}
+
+// handleRole expects one of
+//
+//	ROLE:grant|<jid>|<role>[|<chat>]
+//	ROLE:revoke|<jid>|<role>[|<chat>]
+//	ROLE:list[|<jid>]
+//
+// where an empty chat grants the role everywhere.
+func (b *Bot) handleRole(meta requestMeta, msg string) {
+	parts := strings.Split(strings.TrimPrefix(msg, "ROLE:"), "|")
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+
+	response := map[string]interface{}{
+		"type":      "role_result",
+		"requestId": meta.ID,
+	}
+	var err error
+
+	switch parts[0] {
+	case "grant", "revoke":
+		var user types.JID
+		if user, err = parseUserJID(parts[1]); err != nil {
+			break
+		}
+		user = b.phoneJID(meta.Chat, user)
+		role := strings.ToLower(strings.TrimSpace(parts[2]))
+		if err = validateRoleName(role); err != nil {
+			break
+		}
+		if parts[0] == "grant" {
+			err = b.perms.roles.Grant(user.String(), parts[3], role, b.phoneJID(meta.Chat, meta.Sender).String())
+		} else {
+			var found bool
+			if found, err = b.perms.roles.Revoke(user.String(), parts[3], role); err == nil && !found {
+				err = fmt.Errorf("%s does not have the %s role", user.User, role)
+			}
+		}
+	case "list":
+		jid := ""
+		if parts[1] != "" {
+			var user types.JID
+			if user, err = parseUserJID(parts[1]); err != nil {
+				break
+			}
+			jid = b.phoneJID(meta.Chat, user).String()
+		}
+		var grants []RoleGrant
+		if grants, err = b.perms.roles.List(jid); err == nil {
+			response["roles"] = grants
+		}
+	default:
+		err = fmt.Errorf("unknown role action %q", parts[0])
+	}
+
+	response["status"] = err == nil
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	b.writeResult("ROLE_RESULT", response)
+}
+
+// handlePermission expects PERMISSION:<role>|<command> inside a REQ: envelope
+// and answers whether the envelope's sender may run command in its chat.
+func (b *Bot) handlePermission(meta requestMeta, msg string) {
+	role, name, _ := strings.Cut(strings.TrimPrefix(msg, "PERMISSION:"), "|")
+	role = strings.ToLower(strings.TrimSpace(role))
+	allowed := b.hasRole(meta.Sender, meta.Chat, role)
+	if !allowed {
+		b.sendPermissionDenied(meta, "command", &PermissionError{Name: name, Role: role})
+	}
+
+	b.writeResult("PERMISSION_RESULT", map[string]interface{}{
+		"type":      "permission_result",
+		"requestId": meta.ID,
+		"status":    true,
+		"role":      role,
+		"allowed":   allowed,
+	})
+}
@@ -0,0 +1,429 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Plugin permissions. A plugin declares the ones it needs in its manifest and
+// every action it asks for is checked against them. They limit what the bot
+// does on the plugin's behalf; the plugin process itself is not sandboxed.
+const (
+	// PermReply allows sending text to the chat the command came from.
+	PermReply = "reply"
+	// PermReact allows reacting to the command message.
+	PermReact = "react"
+	// PermMedia allows sending image, video, gif and audio URLs.
+	PermMedia = "media"
+	// PermSendAny allows sending to chats other than the command's chat.
+	PermSendAny = "send_any"
+	// PermSenderInfo includes the sender's JID and push name in requests.
+	PermSenderInfo = "sender_info"
+)
+
+var knownPermissions = map[string]bool{
+	PermReply:      true,
+	PermReact:      true,
+	PermMedia:      true,
+	PermSendAny:    true,
+	PermSenderInfo: true,
+}
+
+// pluginMaxActions bounds how many messages one plugin run can send.
+const pluginMaxActions = 20
+
+// pluginManifest is the plugin.json file in each plugin's directory.
+type pluginManifest struct {
+	Name string `json:"name"`
+	// Exec is the program to run; a path is resolved against the plugin
+	// directory, a bare name is looked up in PATH (e.g. "python3").
+	Exec     string   `json:"exec"`
+	Args     []string `json:"args"`
+	Commands []struct {
+		Name        string   `json:"name"`
+		Aliases     []string `json:"aliases"`
+		Category    string   `json:"category"`
+		Description string   `json:"description"`
+		Usage       string   `json:"usage"`
+		Wait        bool     `json:"wait"`
+		Role        string   `json:"role"`
+	} `json:"commands"`
+	Permissions []string `json:"permissions"`
+	// Env lists the variables passed through from the bot's environment.
+	Env     []string `json:"env"`
+	Timeout int      `json:"timeout"`
+}
+
+type plugin struct {
+	name        string
+	dir         string
+	exec        string
+	args        []string
+	permissions map[string]bool
+	env         []string
+	timeout     time.Duration
+	commands    []string
+}
+
+func (p *plugin) allowed(permission string) bool {
+	return p.permissions[permission]
+}
+
+// pluginCommand runs one command of an external plugin.
+type pluginCommand struct {
+	plugin *plugin
+	info   CommandInfo
+}
+
+func (c *pluginCommand) Info() CommandInfo {
+	return c.info
+}
+
+// pluginRequest is written to the plugin's stdin as a single JSON line.
+type pluginRequest struct {
+	Command   string   `json:"command"`
+	Alias     string   `json:"alias"`
+	Prefix    string   `json:"prefix"`
+	Args      []string `json:"args"`
+	Text      string   `json:"text"`
+	Chat      string   `json:"chat"`
+	IsGroup   bool     `json:"isGroup"`
+	Sender    string   `json:"sender,omitempty"`
+	PushName  string   `json:"pushName,omitempty"`
+	MessageID string   `json:"messageId,omitempty"`
+}
+
+// pluginAction is one JSON line of plugin output.
+type pluginAction struct {
+	// Action is reply, send, media, react or error.
+	Action  string `json:"action"`
+	Chat    string `json:"chat"`
+	Text    string `json:"text"`
+	Type    string `json:"type"`
+	URL     string `json:"url"`
+	Caption string `json:"caption"`
+	Emoji   string `json:"emoji"`
+	Error   string `json:"error"`
+	// Usage marks an error as bad input, answered with the command's usage.
+	Usage bool `json:"usage"`
+}
+
+func (c *pluginCommand) Execute(ctx *CommandContext) error {
+	p := c.plugin
+	req := pluginRequest{
+		Command: c.info.Name,
+		Alias:   ctx.Name,
+		Prefix:  ctx.Prefix,
+		Args:    ctx.Args,
+		Text:    ctx.Text,
+		Chat:    ctx.Chat.String(),
+		IsGroup: ctx.Chat.Server == types.GroupServer,
+	}
+	if ctx.Args == nil {
+		req.Args = []string{}
+	}
+	if p.allowed(PermSenderInfo) {
+		req.Sender = ctx.Sender.String()
+		if ctx.Event != nil {
+			req.PushName = ctx.Event.Info.PushName
+			req.MessageID = ctx.Event.Info.ID
+		}
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, p.exec, p.args...)
+	cmd.Dir = p.dir
+	cmd.Env = p.env
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("plugin %s failed to start: %w", p.name, err)
+	}
+
+	var actionErr error
+	actions := 0
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var action pluginAction
+		if err := json.Unmarshal(line, &action); err != nil {
+			ctx.Bot.Log.Warnf("Plugin %s wrote invalid output: %s", p.name, line)
+			continue
+		}
+		if action.Action == "error" {
+			if action.Usage {
+				actionErr = &UsageError{Reason: action.Error}
+			} else {
+				actionErr = errors.New(action.Error)
+			}
+			continue
+		}
+
+		if actions++; actions > pluginMaxActions {
+			ctx.Bot.Log.Warnf("Plugin %s exceeded %d actions, ignoring the rest", p.name, pluginMaxActions)
+			continue
+		}
+		if err := ctx.Bot.runPluginAction(ctx, c, action); err != nil {
+			ctx.Bot.Log.Errorf("Plugin %s %s failed: %v", p.name, action.Action, err)
+		}
+	}
+
+	waitErr := cmd.Wait()
+	switch {
+	case actionErr != nil:
+		return actionErr
+	case runCtx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("plugin %s timed out after %s", p.name, p.timeout)
+	case waitErr != nil:
+		if msg := lastLine(stderr.String()); msg != "" {
+			return fmt.Errorf("plugin %s: %s", p.name, msg)
+		}
+		return fmt.Errorf("plugin %s: %w", p.name, waitErr)
+	}
+	return nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func (b *Bot) runPluginAction(ctx *CommandContext, c *pluginCommand, action pluginAction) error {
+	chat := ctx.Chat
+	if action.Chat != "" {
+		target, err := types.ParseJID(action.Chat)
+		if err != nil {
+			return fmt.Errorf("invalid chat: %w", err)
+		}
+		if target != chat && !b.pluginAllowed(ctx, c, action.Action, PermSendAny) {
+			return nil
+		}
+		chat = target
+	}
+
+	switch action.Action {
+	case "reply", "send":
+		if !b.pluginAllowed(ctx, c, action.Action, PermReply) {
+			return nil
+		}
+		reply := *ctx
+		reply.Chat = chat
+		return reply.Reply(action.Text)
+	case "media":
+		if !b.pluginAllowed(ctx, c, action.Action, PermMedia) {
+			return nil
+		}
+		mediaType := MediaType(action.Type)
+		switch mediaType {
+		case MediaImage, MediaVideo, MediaGIF, MediaAudio:
+		default:
+			return fmt.Errorf("unsupported media type %q", action.Type)
+		}
+		send := *ctx
+		send.Chat = chat
+		return send.SendMedia(action.URL, mediaType, action.Caption)
+	case "react":
+		if b.pluginAllowed(ctx, c, action.Action, PermReact) {
+			ctx.React(action.Emoji)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", action.Action)
+}
+
+// pluginAllowed checks permission, reporting a denied action as an event.
+func (b *Bot) pluginAllowed(ctx *CommandContext, c *pluginCommand, action, permission string) bool {
+	if c.plugin.allowed(permission) {
+		return true
+	}
+
+	b.Log.Warnf("Plugin %s was denied %s (needs %s)", c.plugin.name, action, permission)
+	b.sendEvent(BotEvent{
+		Type: "plugin_denied",
+		Content: map[string]interface{}{
+			"plugin":     c.plugin.name,
+			"command":    c.info.Name,
+			"action":     action,
+			"permission": permission,
+			"chat":       ctx.Chat.String(),
+		},
+	})
+	return false
+}
+
+// loadPlugins registers the commands of every plugin directory under
+// PLUGINS_DIR. A broken plugin is logged and skipped.
+func (b *Bot) loadPlugins() {
+	dir := os.Getenv("PLUGINS_DIR")
+	if dir == "" {
+		dir = "../plugins"
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.Log.Warnf("Failed to read plugins directory: %v", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		p, manifest, err := loadPlugin(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			b.Log.Errorf("Skipping plugin %s: %v", entry.Name(), err)
+			continue
+		}
+		b.registerPlugin(p, manifest)
+	}
+}
+
+func (b *Bot) registerPlugin(p *plugin, manifest *pluginManifest) {
+	for _, c := range manifest.Commands {
+		category := c.Category
+		if category == "" {
+			category = "plugins"
+		}
+		cmd := &pluginCommand{
+			plugin: p,
+			info: CommandInfo{
+				Name:        c.Name,
+				Aliases:     c.Aliases,
+				Category:    category,
+				Description: c.Description,
+				Usage:       c.Usage,
+				Wait:        c.Wait,
+				Kind:        JobPlugin,
+				Role:        c.Role,
+			},
+		}
+		if err := b.router.Register(cmd); err != nil {
+			b.Log.Errorf("Plugin %s: %v", p.name, err)
+			continue
+		}
+		p.commands = append(p.commands, c.Name)
+	}
+
+	sort.Strings(p.commands)
+	b.plugins = append(b.plugins, p)
+	b.Log.Infof("Loaded plugin %s with commands %v", p.name, p.commands)
+}
+
+func readPluginManifest(dir string) (*pluginManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "plugin.json"))
+	if err != nil {
+		return nil, err
+	}
+	var manifest pluginManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid plugin.json: %w", err)
+	}
+	return &manifest, nil
+}
+
+func loadPlugin(dir string) (*plugin, *pluginManifest, error) {
+	manifest, err := readPluginManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest.Name == "" || manifest.Exec == "" || len(manifest.Commands) == 0 {
+		return nil, nil, errors.New("plugin.json needs a name, exec and at least one command")
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	executable := manifest.Exec
+	if strings.ContainsRune(executable, '/') || strings.ContainsRune(executable, filepath.Separator) {
+		executable = filepath.Join(dir, executable)
+		if rel, err := filepath.Rel(dir, executable); err != nil || strings.HasPrefix(rel, "..") {
+			return nil, nil, fmt.Errorf("exec %s is outside the plugin directory", manifest.Exec)
+		}
+	} else if executable, err = exec.LookPath(executable); err != nil {
+		return nil, nil, err
+	}
+
+	p := &plugin{
+		name:        manifest.Name,
+		dir:         dir,
+		exec:        executable,
+		args:        manifest.Args,
+		permissions: make(map[string]bool),
+		timeout:     time.Duration(envInt("PLUGIN_TIMEOUT", 30)) * time.Second,
+	}
+	if manifest.Timeout > 0 {
+		p.timeout = time.Duration(manifest.Timeout) * time.Second
+	}
+
+	for _, permission := range manifest.Permissions {
+		if !knownPermissions[permission] {
+			return nil, nil, fmt.Errorf("unknown permission %q", permission)
+		}
+		p.permissions[permission] = true
+	}
+
+	// Plugins only see the variables they declared, not the bot's secrets.
+	p.env = []string{"PLUGIN_NAME=" + p.name, "PLUGIN_DIR=" + dir}
+	for _, key := range append([]string{"PATH", "HOME", "LANG", "TMPDIR", "SYSTEMROOT"}, manifest.Env...) {
+		if value, ok := os.LookupEnv(key); ok {
+			p.env = append(p.env, key+"="+value)
+		}
+	}
+	return p, manifest, nil
+}
+
+// handlePlugins expects PLUGINS: and lists the loaded plugins.
+func (b *Bot) handlePlugins() {
+	plugins := make([]map[string]interface{}, 0, len(b.plugins))
+	for _, p := range b.plugins {
+		permissions := make([]string, 0, len(p.permissions))
+		for permission := range p.permissions {
+			permissions = append(permissions, permission)
+		}
+		sort.Strings(permissions)
+		plugins = append(plugins, map[string]interface{}{
+			"name":        p.name,
+			"dir":         p.dir,
+			"commands":    p.commands,
+			"permissions": permissions,
+		})
+	}
+
+	b.writeResult("PLUGINS_RESULT", map[string]interface{}{
+		"type":    "plugins_result",
+		"status":  true,
+		"plugins": plugins,
+	})
+}
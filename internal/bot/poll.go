@@ -0,0 +1,302 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const pollsSchema = `
+CREATE TABLE IF NOT EXISTS awara_polls (
+	id         TEXT    PRIMARY KEY,
+	chat       TEXT    NOT NULL,
+	name       TEXT    NOT NULL,
+	options    TEXT    NOT NULL,
+	selectable INTEGER NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS awara_poll_votes (
+	poll_id  TEXT    NOT NULL,
+	voter    TEXT    NOT NULL,
+	selected TEXT    NOT NULL,
+	voted_at INTEGER NOT NULL,
+	PRIMARY KEY (poll_id, voter)
+);`
+
+type pollState struct {
+	chat       types.JID
+	name       string
+	options    []string
+	selectable int
+}
+
+// pollStore keeps sent polls and their votes so tallies survive a restart.
+// whatsmeow persists the poll secrets DecryptPollVote needs on its own.
+type pollStore struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+func newPollStore(db *sql.DB) (*pollStore, error) {
+	if _, err := db.Exec(pollsSchema); err != nil {
+		return nil, err
+	}
+	return &pollStore{db: db}, nil
+}
+
+func (s *pollStore) Add(id types.MessageID, poll *pollState) error {
+	options, err := json.Marshal(poll.options)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT OR REPLACE INTO awara_polls (id, chat, name, options, selectable, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		string(id), poll.chat.String(), poll.name, string(options), poll.selectable, time.Now().Unix(),
+	)
+	return err
+}
+
+func (s *pollStore) get(id types.MessageID) (*pollState, error) {
+	var chat, options string
+	poll := &pollState{}
+	err := s.db.QueryRow(
+		`SELECT chat, name, options, selectable FROM awara_polls WHERE id = ?`, string(id),
+	).Scan(&chat, &poll.name, &options, &poll.selectable)
+	if err != nil {
+		return nil, err
+	}
+	if poll.chat, err = types.ParseJID(chat); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(options), &poll.options); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// RecordVote replaces voter's selection with the options matching hashes.
+// It returns sql.ErrNoRows for polls this bot did not send.
+func (s *pollStore) RecordVote(id types.MessageID, voter string, hashes [][]byte) (*pollState, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, err := s.get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		for _, option := range poll.options {
+			optionHash := sha256.Sum256([]byte(option))
+			if string(optionHash[:]) == string(hash) {
+				selected = append(selected, option)
+				break
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		_, err = s.db.Exec(`DELETE FROM awara_poll_votes WHERE poll_id = ? AND voter = ?`, string(id), voter)
+	} else {
+		data, _ := json.Marshal(selected)
+		_, err = s.db.Exec(
+			`INSERT OR REPLACE INTO awara_poll_votes (poll_id, voter, selected, voted_at) VALUES (?, ?, ?, ?)`,
+			string(id), voter, string(data), time.Now().Unix(),
+		)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return poll, selected, nil
+}
+
+func (s *pollStore) Tally(id types.MessageID) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT voter, selected FROM awara_poll_votes WHERE poll_id = ? ORDER BY voted_at`, string(id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(poll.options))
+	voters := make(map[string][]string, len(poll.options))
+	for _, option := range poll.options {
+		counts[option] = 0
+		voters[option] = []string{}
+	}
+	totalVoters := 0
+	for rows.Next() {
+		var voter, data string
+		if err := rows.Scan(&voter, &data); err != nil {
+			return nil, err
+		}
+		var selected []string
+		if err := json.Unmarshal([]byte(data), &selected); err != nil {
+			return nil, err
+		}
+		totalVoters++
+		for _, option := range selected {
+			counts[option]++
+			voters[option] = append(voters[option], voter)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	options := make([]map[string]interface{}, 0, len(poll.options))
+	for _, option := range poll.options {
+		options = append(options, map[string]interface{}{
+			"name":   option,
+			"votes":  counts[option],
+			"voters": voters[option],
+		})
+	}
+
+	return map[string]interface{}{
+		"pollId":      id,
+		"chat":        poll.chat.String(),
+		"name":        poll.name,
+		"selectable":  poll.selectable,
+		"totalVoters": totalVoters,
+		"options":     options,
+	}, nil
+}
+
+// handleSendPoll expects SEND_POLL:<jid>|<selectable>|<question>|<option>|<option>...
+func (b *Bot) handleSendPoll(msg string) {
+	parts := strings.Split(strings.TrimPrefix(msg, "SEND_POLL:"), "|")
+	if len(parts) < 5 {
+		b.Log.Errorf("Invalid SEND_POLL format")
+		return
+	}
+
+	jid, err := types.ParseJID(parts[0])
+	if err != nil {
+		b.Log.Errorf("JID parse error: %v", err)
+		return
+	}
+
+	selectable, err := strconv.Atoi(parts[1])
+	if err != nil {
+		selectable = 1
+	}
+
+	name := strings.ReplaceAll(parts[2], "{{NL}}", "\n")
+	options := make([]string, 0, len(parts)-3)
+	for _, option := range parts[3:] {
+		if option = strings.TrimSpace(option); option != "" {
+			options = append(options, option)
+		}
+	}
+	if len(options) < 2 {
+		b.Log.Errorf("Poll needs at least 2 options")
+		return
+	}
+
+	pollMsg := b.Client.BuildPollCreation(name, options, selectable)
+	b.queueSend(jid, pollMsg, func(resp whatsmeow.SendResponse, err error) {
+		if err != nil {
+			b.Log.Errorf("Poll send error: %v", err)
+			return
+		}
+		b.registerPoll(jid, resp, pollMsg, name, options)
+	})
+}
+
+func (b *Bot) registerPoll(jid types.JID, resp whatsmeow.SendResponse, pollMsg *waProto.Message, name string, options []string) {
+	err := b.polls.Add(resp.ID, &pollState{
+		chat:       jid,
+		name:       name,
+		options:    options,
+		selectable: int(pollMsg.GetPollCreationMessage().GetSelectableOptionsCount()),
+	})
+	if err != nil {
+		b.Log.Errorf("Poll save error: %v", err)
+	}
+
+	b.sendEvent(BotEvent{
+		Type: "poll_created",
+		Content: map[string]interface{}{
+			"chat":    jid.String(),
+			"pollId":  resp.ID,
+			"name":    name,
+			"options": options,
+		},
+	})
+}
+
+// handlePollResult expects POLL_RESULT:<pollId> and answers with the current tally.
+func (b *Bot) handlePollResult(meta requestMeta, msg string) {
+	pollID := types.MessageID(strings.TrimSpace(strings.TrimPrefix(msg, "POLL_RESULT:")))
+
+	response := map[string]interface{}{
+		"type":      "poll_result",
+		"requestId": meta.ID,
+		"status":    true,
+	}
+	result, err := b.polls.Tally(pollID)
+	switch {
+	case err == nil:
+		response["result"] = result
+	case errors.Is(err, sql.ErrNoRows):
+		response["status"] = false
+		response["error"] = fmt.Sprintf("poll %s not found", pollID)
+	default:
+		response["status"] = false
+		response["error"] = err.Error()
+	}
+
+	b.writeResult("POLL_RESULT", response)
+}
+
+func (b *Bot) handlePollVote(msg *events.Message) {
+	pollKey := msg.Message.GetPollUpdateMessage().GetPollCreationMessageKey()
+
+	vote, err := b.Client.DecryptPollVote(msg)
+	if err != nil {
+		b.Log.Errorf("Poll vote decrypt error: %v", err)
+		return
+	}
+
+	voter := msg.Info.Sender.ToNonAD().String()
+	poll, selected, err := b.polls.RecordVote(types.MessageID(pollKey.GetID()), voter, vote.GetSelectedOptions())
+	if errors.Is(err, sql.ErrNoRows) {
+		b.Log.Warnf("Vote for unknown poll %s", pollKey.GetID())
+		return
+	} else if err != nil {
+		b.Log.Errorf("Poll vote save error: %v", err)
+		return
+	}
+
+	b.sendEvent(BotEvent{
+		Type: "poll_vote",
+		Content: map[string]interface{}{
+			"chat":      poll.chat.String(),
+			"pollId":    pollKey.GetID(),
+			"from":      voter,
+			"pushName":  msg.Info.PushName,
+			"selected":  selected,
+			"messageId": msg.Info.ID,
+		},
+	})
+}
@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressTracker reports byte-level progress of one transfer stage as
+// throttled job_progress events tied to a request ID.
+type progressTracker struct {
+	bot       *Bot
+	requestID string
+	chat      string
+	stage     string
+	interval  time.Duration
+
+	mu       sync.Mutex
+	total    int64
+	bytes    int64
+	started  time.Time
+	lastEmit time.Time
+}
+
+// newProgress returns nil for requests without an ID; all methods are no-ops on nil.
+func (b *Bot) newProgress(meta requestMeta, stage string) *progressTracker {
+	if meta.ID == "" {
+		return nil
+	}
+	return &progressTracker{
+		bot:       b,
+		requestID: meta.ID,
+		chat:      meta.Chat.String(),
+		stage:     stage,
+		interval:  time.Duration(envInt("JOBS_PROGRESS_INTERVAL", 1000)) * time.Millisecond,
+		started:   time.Now(),
+	}
+}
+
+func (p *progressTracker) SetTotal(total int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.total = total
+	p.mu.Unlock()
+}
+
+func (p *progressTracker) Add(n int) {
+	if p == nil || n <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	p.bytes += int64(n)
+	due := time.Since(p.lastEmit) >= p.interval
+	if due {
+		p.lastEmit = time.Now()
+	}
+	p.mu.Unlock()
+
+	if due {
+		p.emit(false)
+	}
+}
+
+// Finish emits a final event so listeners always see the stage complete.
+func (p *progressTracker) Finish() {
+	if p == nil {
+		return
+	}
+	p.emit(true)
+}
+
+// Complete marks a stage that finished without streaming, like a reused upload.
+func (p *progressTracker) Complete(total int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.total, p.bytes = total, total
+	p.mu.Unlock()
+	p.emit(true)
+}
+
+func (p *progressTracker) emit(done bool) {
+	p.mu.Lock()
+	bytes, total := p.bytes, p.total
+	elapsed := time.Since(p.started).Seconds()
+	p.mu.Unlock()
+
+	percent, eta := -1.0, -1
+	if done && total <= 0 {
+		total = bytes
+	}
+	var speed float64
+	if elapsed > 0 {
+		speed = float64(bytes) / elapsed
+	}
+	if total > 0 {
+		percent = float64(bytes) * 100 / float64(total)
+		if speed > 0 {
+			eta = int(float64(total-bytes) / speed)
+		}
+	}
+
+	p.bot.sendEvent(BotEvent{
+		Type: "job_progress",
+		Content: map[string]interface{}{
+			"requestId": p.requestID,
+			"chat":      p.chat,
+			"stage":     p.stage,
+			"bytes":     bytes,
+			"total":     total,
+			"percent":   percent,
+			"speed":     int64(speed),
+			"eta":       eta,
+			"done":      done,
+		},
+	})
+}
+
+// progressReader counts bytes read through it.
+type progressReader struct {
+	r        io.Reader
+	progress *progressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.progress.Add(n)
+	return n, err
+}
+
+// uploadProgressFile is the temp file handed to Client.UploadReader. The
+// plaintext is encrypted into it first; once whatsmeow seeks back to the
+// start, every read is the HTTP upload streaming the ciphertext. The file is
+// not embedded: that would promote (*os.File).WriteTo, which io.Copy prefers
+// over Read, and the upload would go uncounted.
+type uploadProgressFile struct {
+	f         *os.File
+	progress  *progressTracker
+	uploading bool
+}
+
+func (f *uploadProgressFile) Write(p []byte) (int, error) {
+	return f.f.Write(p)
+}
+
+func (f *uploadProgressFile) Close() error {
+	return f.f.Close()
+}
+
+func (f *uploadProgressFile) Seek(offset int64, whence int) (int64, error) {
+	pos, err := f.f.Seek(offset, whence)
+	if err == nil && pos == 0 {
+		if info, statErr := f.f.Stat(); statErr == nil {
+			f.progress.SetTotal(info.Size())
+		}
+		f.uploading = true
+	}
+	return pos, err
+}
+
+func (f *uploadProgressFile) Read(p []byte) (int, error) {
+	n, err := f.f.Read(p)
+	if f.uploading {
+		f.progress.Add(n)
+	}
+	return n, err
+}
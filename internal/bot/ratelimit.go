@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitError is returned when a request is refused by the rate limiter.
+type RateLimitError struct {
+	Scope      string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("you're sending requests too fast, please try again in %d seconds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// tokenLimiter keeps one token bucket per key. Buckets start full, hold at
+// most capacity tokens and regain refill tokens per second.
+type tokenLimiter struct {
+	capacity float64
+	refill   float64
+	buckets  map[string]*tokenBucket
+}
+
+func newTokenLimiter(capacity, perMinute int) *tokenLimiter {
+	return &tokenLimiter{
+		capacity: float64(capacity),
+		refill:   float64(perMinute) / 60,
+		buckets:  make(map[string]*tokenBucket),
+	}
+}
+
+func (l *tokenLimiter) bucket(key string, now time.Time) *tokenBucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.capacity, last: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens = math.Min(l.capacity, b.tokens+now.Sub(b.last).Seconds()*l.refill)
+	b.last = now
+	return b
+}
+
+// wait is how long key has to wait before cost tokens are available.
+func (l *tokenLimiter) wait(key string, cost float64, now time.Time) time.Duration {
+	if l.capacity <= 0 {
+		return 0
+	}
+	b := l.bucket(key, now)
+	if b.tokens >= cost {
+		return 0
+	}
+	if l.refill <= 0 || cost > l.capacity {
+		return time.Hour
+	}
+	return time.Duration((cost - b.tokens) / l.refill * float64(time.Second))
+}
+
+func (l *tokenLimiter) take(key string, cost float64) {
+	if b, ok := l.buckets[key]; ok {
+		b.tokens -= cost
+	}
+}
+
+// prune drops buckets that have refilled completely; they behave exactly like new ones.
+func (l *tokenLimiter) prune(now time.Time) {
+	for key := range l.buckets {
+		if l.bucket(key, now).tokens >= l.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimiter applies a per-sender and a per-chat budget to scheduled
+// requests. Commands and IPC ops can be given their own cost; anything else
+// costs what its job kind does.
+type rateLimiter struct {
+	mu       sync.Mutex
+	sender   *tokenLimiter
+	chat     *tokenLimiter
+	costs    map[JobKind]float64
+	commands map[string]float64
+}
+
+func newRateLimiterFromEnv() *rateLimiter {
+	r := &rateLimiter{
+		sender: newTokenLimiter(envInt("RATE_USER_CAPACITY", 10), envInt("RATE_USER_PER_MINUTE", 6)),
+		chat:   newTokenLimiter(envInt("RATE_CHAT_CAPACITY", 30), envInt("RATE_CHAT_PER_MINUTE", 20)),
+		costs: map[JobKind]float64{
+			JobDownload: 3,
+			JobEnhance:  3,
+			JobChatbot:  1,
+			JobMedia:    1,
+			JobPlugin:   1,
+		},
+		commands: parseCommandCosts(os.Getenv("RATE_COMMAND_COSTS")),
+	}
+
+	for kind := range r.costs {
+		if v, err := strconv.ParseFloat(os.Getenv("RATE_COST_"+strings.ToUpper(string(kind))), 64); err == nil && v >= 0 {
+			r.costs[kind] = v
+		}
+	}
+
+	go func() {
+		for range time.Tick(10 * time.Minute) {
+			r.mu.Lock()
+			now := time.Now()
+			r.sender.prune(now)
+			r.chat.prune(now)
+			r.mu.Unlock()
+		}
+	}()
+	return r
+}
+
+// parseCommandCosts reads a comma separated list of name=cost pairs, such as
+// "ytmp4=5,tiktok=2,batch_download=10". Names are command names or lowercase
+// IPC ops.
+func parseCommandCosts(spec string) map[string]float64 {
+	costs := make(map[string]float64)
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" {
+			continue
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && v >= 0 {
+			costs[strings.ToLower(strings.TrimSpace(name))] = v
+		}
+	}
+	return costs
+}
+
+// cost is what a request named name costs, falling back to its kind's cost.
+// Delivery ops are free unless configured: the TS layer sends them while
+// answering a request that was already charged, and charging again would cut
+// off e.g. a carousel halfway through.
+func (r *rateLimiter) cost(kind JobKind, name string) float64 {
+	if cost, ok := r.commands[name]; ok {
+		return cost
+	}
+	if isDeliveryOp(name) {
+		return 0
+	}
+	return r.costs[kind]
+}
+
+// isDeliveryOp reports whether name is an IPC op that delivers media for an
+// earlier request: SEND_*, SEND_URL_*, SEND_ALBUM and DOWNLOAD_MEDIA.
+func isDeliveryOp(name string) bool {
+	return strings.HasPrefix(name, "send_") || name == "download_media"
+}
+
+// costName names a job's command for the cost table: the canonical command
+// name for COMMAND: jobs, or the lowercase op such as "download" otherwise.
+func (b *Bot) costName(command string) string {
+	op, rest, _ := strings.Cut(command, ":")
+	if op == "COMMAND" {
+		_, name, _, _ := b.router.parse(rest)
+		if cmd := b.router.Lookup(name); cmd != nil {
+			return cmd.Info().Name
+		}
+		return name
+	}
+	return strings.ToLower(op)
+}
+
+// Allow charges the cost of the request named name to both the sender and
+// the chat, or to neither when either budget is exhausted.
+func (r *rateLimiter) Allow(kind JobKind, name, sender, chat string) error {
+	cost := r.cost(kind, name)
+	if cost <= 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if sender != "" {
+		if wait := r.sender.wait(sender, cost, now); wait > 0 {
+			return &RateLimitError{Scope: "sender", RetryAfter: wait}
+		}
+	}
+	if chat != "" {
+		if wait := r.chat.wait(chat, cost, now); wait > 0 {
+			return &RateLimitError{Scope: "chat", RetryAfter: wait}
+		}
+	}
+
+	if sender != "" {
+		r.sender.take(sender, cost)
+	}
+	if chat != "" {
+		r.chat.take(chat, cost)
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// Router modes, picked with ROUTER_MODE.
+const (
+	// RouterIPC forwards every message to the TS layer, which parses commands.
+	RouterIPC = "ipc"
+	// RouterHybrid runs commands registered in Go and forwards everything else.
+	RouterHybrid = "hybrid"
+	// RouterNative handles all messages in Go, so the binary runs on its own.
+	RouterNative = "native"
+)
+
+// defaultPrefixes matches the prefix characters accepted by src/utils/prefix.ts.
+const defaultPrefixes = "°•π÷×¶∆£¢€¥®™✓_=|~!?#/$%^&.+-,\\©"
+
+// CommandInfo describes a command to the router and the menu.
+type CommandInfo struct {
+	Name        string
+	Aliases     []string
+	Category    string
+	Description string
+	// Usage is shown after the command name when it is called wrongly, e.g. "<url>".
+	Usage string
+	// Wait reacts with ⏳ while the command runs and ✅ or ❌ when it ends.
+	Wait bool
+	// Kind runs the command in that job pool instead of its own goroutine.
+	Kind JobKind
+	// Role is needed to run the command; empty allows everyone. COMMAND_ROLES
+	// overrides it per command name.
+	Role string
+}
+
+// Command is a chat command run by the router. Built-in commands are
+// FuncCommands; plugins loaded from PLUGINS_DIR implement it as well.
+type Command interface {
+	Info() CommandInfo
+	Execute(ctx *CommandContext) error
+}
+
+// FuncCommand is a Command backed by a function.
+type FuncCommand struct {
+	CommandInfo
+	Run func(ctx *CommandContext) error
+}
+
+func (c *FuncCommand) Info() CommandInfo {
+	return c.CommandInfo
+}
+
+func (c *FuncCommand) Execute(ctx *CommandContext) error {
+	return c.Run(ctx)
+}
+
+var (
+	registryMu     sync.Mutex
+	registeredCmds []Command
+)
+
+// RegisterCommand adds cmd to every bot created afterwards, so commands can be
+// compiled in from an init function without touching the built-in list.
+func RegisterCommand(cmd Command) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registeredCmds = append(registeredCmds, cmd)
+}
+
+func registeredCommands() []Command {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return append([]Command(nil), registeredCmds...)
+}
+
+// UsageError is returned by a command that was called with bad arguments; the
+// router replies with the command's usage instead of a failure message.
+type UsageError struct {
+	Reason string
+}
+
+func (e *UsageError) Error() string {
+	return e.Reason
+}
+
+// CommandContext is what a command gets to work with.
+type CommandContext struct {
+	Bot     *Bot
+	Command Command
+	Info    CommandInfo
+	Chat    types.JID
+	Sender  types.JID
+	Prefix  string
+	Name    string
+	Args    []string
+	Text    string
+	// Event is the message that triggered the command. It is nil when the
+	// command came in over IPC or was resumed after a restart.
+	Event *events.Message
+	meta  requestMeta
+	rest  string
+}
+
+// ArgText returns everything after the command name, line breaks included.
+func (c *CommandContext) ArgText() string {
+	return c.rest
+}
+
+func (c *CommandContext) Reply(text string) error {
+	_, err := c.Bot.send(c.Chat, &waProto.Message{Conversation: proto.String(text)})
+	return err
+}
+
+func (c *CommandContext) React(emoji string) {
+	if c.Event == nil {
+		return
+	}
+	reaction := c.Bot.Client.BuildReaction(c.Chat, c.Sender, c.Event.Info.ID, emoji)
+	c.Bot.queueSend(c.Chat, reaction, func(_ whatsmeow.SendResponse, err error) {
+		if err != nil {
+			c.Bot.Log.Errorf("Failed to send reaction: %v", err)
+		}
+	})
+}
+
+// SendMedia fetches url and sends it to the chat as mediaType.
+func (c *CommandContext) SendMedia(url string, mediaType MediaType, caption string) error {
+	data, _, err := c.Bot.fetcher.FetchWithProgress(url, mediaType, c.Bot.newProgress(c.meta, "download"))
+	if err != nil {
+		return err
+	}
+	return c.Bot.uploadAndSendMedia(c.Chat, data, mediaType, caption, c.Bot.newProgress(c.meta, "upload"))
+}
+
+// Image downloads the image the command was sent with or replied to.
+func (c *CommandContext) Image() ([]byte, error) {
+	if c.Event == nil {
+		return nil, &UsageError{Reason: "send or reply to an image"}
+	}
+	if img := c.Event.Message.GetImageMessage(); img != nil {
+		return c.Bot.Client.Download(img)
+	}
+	quoted := c.Event.Message.GetExtendedTextMessage().GetContextInfo().GetQuotedMessage()
+	if img := quoted.GetImageMessage(); img != nil {
+		return c.Bot.Client.Download(img)
+	}
+	return nil, &UsageError{Reason: "send or reply to an image"}
+}
+
+type commandRouter struct {
+	mode     string
+	prefixes string
+	commands map[string]Command
+	aliases  map[string]string
+}
+
+func newCommandRouterFromEnv() *commandRouter {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("ROUTER_MODE")))
+	if mode != RouterHybrid && mode != RouterNative {
+		mode = RouterIPC
+	}
+	prefixes := os.Getenv("COMMAND_PREFIXES")
+	if prefixes == "" {
+		prefixes = defaultPrefixes
+	}
+
+	return &commandRouter{
+		mode:     mode,
+		prefixes: prefixes,
+		commands: make(map[string]Command),
+		aliases:  make(map[string]string),
+	}
+}
+
+// Register adds cmd under its name and aliases, refusing names that are taken.
+func (r *commandRouter) Register(cmd Command) error {
+	info := cmd.Info()
+	if info.Name == "" {
+		return errors.New("command needs a name")
+	}
+
+	names := append([]string{info.Name}, info.Aliases...)
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if _, ok := r.commands[name]; ok {
+			return fmt.Errorf("command name %q is already registered", name)
+		}
+		if _, ok := r.aliases[name]; ok {
+			return fmt.Errorf("command name %q is already an alias", name)
+		}
+	}
+
+	r.commands[strings.ToLower(info.Name)] = cmd
+	for _, alias := range info.Aliases {
+		r.aliases[strings.ToLower(alias)] = strings.ToLower(info.Name)
+	}
+	return nil
+}
+
+func (r *commandRouter) Lookup(name string) Command {
+	name = strings.ToLower(name)
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
+	return r.commands[name]
+}
+
+// Categories groups the registered commands by category, sorted by name.
+func (r *commandRouter) Categories() map[string][]CommandInfo {
+	categories := make(map[string][]CommandInfo)
+	for _, cmd := range r.commands {
+		info := cmd.Info()
+		categories[info.Category] = append(categories[info.Category], info)
+	}
+	for _, cmds := range categories {
+		sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	}
+	return categories
+}
+
+// parse splits text into prefix, command name and arguments the same way
+// extractCommand does on the TS side: the prefix is optional.
+func (r *commandRouter) parse(text string) (prefix, name string, args []string, rest string) {
+	text = strings.TrimSpace(text)
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", "", nil, ""
+	}
+
+	name = fields[0]
+	rest = strings.TrimSpace(text[len(name):])
+	if first, size := utf8.DecodeRuneInString(name); strings.ContainsRune(r.prefixes, first) {
+		prefix, name = name[:size], name[size:]
+	}
+	return prefix, strings.ToLower(name), fields[1:], rest
+}
+
+// routeMessage runs text as a command when the router mode allows it. It
+// reports whether the message was handled, in which case it must not be
+// forwarded to the TS layer.
+func (b *Bot) routeMessage(evt *events.Message, text string) bool {
+	if b.router.mode == RouterIPC {
+		return false
+	}
+
+	if text == "" {
+		text = evt.Message.GetImageMessage().GetCaption()
+	}
+
+	meta := requestMeta{
+		ID:     "cmd-" + evt.Info.ID,
+		Chat:   evt.Info.Chat,
+		Sender: evt.Info.Sender.ToNonAD(),
+	}
+	handled := b.dispatchCommand(evt, meta, text)
+	if b.router.mode == RouterNative {
+		if !handled && !evt.Info.IsGroup && text != "" {
+			b.dispatchCommand(evt, meta, "ai "+text)
+		}
+		return true
+	}
+	return handled
+}
+
+// dispatchCommand looks up and starts the command in text, reporting whether
+// there was one. evt is nil for commands that didn't come from a message.
+func (b *Bot) dispatchCommand(evt *events.Message, meta requestMeta, text string) bool {
+	prefix, name, args, rest := b.router.parse(text)
+	cmd := b.router.Lookup(name)
+	if cmd == nil {
+		return false
+	}
+
+	ctx := &CommandContext{
+		Bot:     b,
+		Command: cmd,
+		Info:    cmd.Info(),
+		Chat:    meta.Chat,
+		Sender:  meta.Sender,
+		Prefix:  prefix,
+		Name:    name,
+		Args:    args,
+		Text:    text,
+		Event:   evt,
+		meta:    meta,
+		rest:    rest,
+	}
+
+	// a command without a sender comes from the host process, like IPC ops
+	if role := b.perms.commandRole(ctx.Info); !meta.Sender.IsEmpty() && !b.hasRole(meta.Sender, meta.Chat, role) {
+		err := &PermissionError{Name: ctx.Info.Name, Role: role}
+		b.sendPermissionDenied(meta, "command", err)
+		go func() {
+			if replyErr := ctx.Reply("🚫 " + err.Error()); replyErr != nil {
+				b.Log.Errorf("Failed to send permission notice: %v", replyErr)
+			}
+		}()
+		return true
+	}
+
+	b.Log.Infof("Command %s from %s in %s", ctx.Info.Name, ctx.Sender, ctx.Chat)
+	if ctx.Info.Kind == "" {
+		go b.runCommand(ctx)
+		return true
+	}
+
+	b.jobs.Submit(ctx.Info.Kind, meta, "COMMAND:"+text, func() error {
+		return b.runCommand(ctx)
+	}, func(err error) {
+		b.replyCommandError(ctx, err)
+	})
+	return true
+}
+
+func (b *Bot) runCommand(ctx *CommandContext) (err error) {
+	if ctx.Info.Wait {
+		ctx.React("⏳")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			b.Log.Errorf("Command %s panicked: %v", ctx.Info.Name, r)
+			err = errors.New("internal error")
+		}
+		if err != nil {
+			b.replyCommandError(ctx, err)
+		} else if ctx.Info.Wait {
+			ctx.React("✅")
+		}
+	}()
+	return ctx.Command.Execute(ctx)
+}
+
+func (b *Bot) replyCommandError(ctx *CommandContext, err error) {
+	if ctx.Info.Wait {
+		ctx.React("❌")
+	}
+
+	var usage *UsageError
+	var reply string
+	switch {
+	case errors.As(err, &usage):
+		reply = fmt.Sprintf("⚠️ %s\nUsage: %s%s %s", usage.Reason, ctx.Prefix, ctx.Info.Name, ctx.Info.Usage)
+	case errors.Is(err, ErrQueueFull):
+		reply = "🕒 " + err.Error()
+	default:
+		var limited *RateLimitError
+		if errors.As(err, &limited) {
+			reply = fmt.Sprintf("🐢 Slow down a little, please try again in %d seconds.", int(math.Ceil(limited.RetryAfter.Seconds())))
+		} else {
+			b.Log.Errorf("Command %s failed: %v", ctx.Info.Name, err)
+			reply = fmt.Sprintf("❌ %s failed: %s", ctx.Info.Name, fetchErrorReason(err))
+		}
+	}
+
+	if replyErr := ctx.Reply(reply); replyErr != nil {
+		b.Log.Errorf("Failed to send command error: %v", replyErr)
+	}
+}
+
+// handleCommand expects COMMAND:<text>, with the chat and sender taken from the
+// REQ: envelope. It runs a Go-side command without a WhatsApp message behind it.
+func (b *Bot) handleCommand(meta requestMeta, msg string) {
+	text := strings.ReplaceAll(strings.TrimPrefix(msg, "COMMAND:"), "{{NL}}", "\n")
+	if meta.Chat.IsEmpty() {
+		b.Log.Errorf("COMMAND needs a REQ: envelope with the chat")
+		return
+	}
+	if !b.dispatchCommand(nil, meta, text) {
+		b.Log.Warnf("Unknown command %q", text)
+	}
+}
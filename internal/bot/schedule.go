@@ -0,0 +1,440 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // schedules name IANA zones even on hosts without a zoneinfo database
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const schedulesSchema = `
+CREATE TABLE IF NOT EXISTS awara_schedules (
+	id         TEXT    PRIMARY KEY,
+	chat       TEXT    NOT NULL,
+	cron       TEXT    NOT NULL DEFAULT '',
+	timezone   TEXT    NOT NULL,
+	type       TEXT    NOT NULL,
+	content    TEXT    NOT NULL,
+	caption    TEXT    NOT NULL DEFAULT '',
+	next_run   INTEGER NOT NULL,
+	last_run   INTEGER NOT NULL DEFAULT 0,
+	active     INTEGER NOT NULL DEFAULT 1,
+	created_by TEXT    NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS awara_schedules_due ON awara_schedules (active, next_run);`
+
+// Catch-up policies for runs missed while the bot was offline.
+const (
+	catchUpSkip = "skip" // drop missed runs and wait for the next one
+	catchUpOnce = "once" // send once, however many runs were missed
+	catchUpAll  = "all"  // send every missed run, up to scheduleMaxCatchUp
+)
+
+const (
+	scheduleTick       = 20 * time.Second
+	scheduleGrace      = 2 * time.Minute
+	scheduleMaxCatchUp = 10
+)
+
+// Schedule is a one-off (empty Cron) or recurring message.
+type Schedule struct {
+	ID        string `json:"id"`
+	Chat      string `json:"chat"`
+	Cron      string `json:"cron,omitempty"`
+	Timezone  string `json:"timezone"`
+	Type      string `json:"type"`
+	Content   string `json:"content"`
+	Caption   string `json:"caption,omitempty"`
+	NextRun   int64  `json:"nextRun"`
+	LastRun   int64  `json:"lastRun,omitempty"`
+	Active    bool   `json:"active"`
+	CreatedBy string `json:"createdBy,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// next returns the run after t, or the zero time for one-off schedules.
+func (s *Schedule) next(t time.Time) (time.Time, error) {
+	if s.Cron == "" {
+		return time.Time{}, nil
+	}
+	spec, err := parseCron(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return spec.Next(t.In(loc)), nil
+}
+
+type scheduleStore struct {
+	db *sql.DB
+}
+
+func newScheduleStore(db *sql.DB) (*scheduleStore, error) {
+	if _, err := db.Exec(schedulesSchema); err != nil {
+		return nil, err
+	}
+	return &scheduleStore{db: db}, nil
+}
+
+func (s *scheduleStore) Add(sched *Schedule) error {
+	_, err := s.db.Exec(`
+		INSERT INTO awara_schedules (id, chat, cron, timezone, type, content, caption, next_run, active, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)`,
+		sched.ID, sched.Chat, sched.Cron, sched.Timezone, sched.Type, sched.Content, sched.Caption,
+		sched.NextRun, sched.CreatedBy, sched.CreatedAt,
+	)
+	return err
+}
+
+func (s *scheduleStore) Cancel(id string) (bool, error) {
+	res, err := s.db.Exec(`UPDATE awara_schedules SET active = 0 WHERE id = ? AND active = 1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Advance records a run and moves the schedule to next; a zero next deactivates it.
+func (s *scheduleStore) Advance(id string, lastRun, next time.Time) error {
+	active, nextRun := 1, next.Unix()
+	if next.IsZero() {
+		active, nextRun = 0, 0
+	}
+	_, err := s.db.Exec(
+		`UPDATE awara_schedules SET last_run = ?, next_run = ?, active = ? WHERE id = ?`,
+		lastRun.Unix(), nextRun, active, id,
+	)
+	return err
+}
+
+func (s *scheduleStore) Get(id string) (*Schedule, error) {
+	schedules, err := s.query(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(schedules) == 0 {
+		return nil, fmt.Errorf("schedule %s not found", id)
+	}
+	return &schedules[0], nil
+}
+
+func (s *scheduleStore) Due(now time.Time) ([]Schedule, error) {
+	return s.query(`WHERE active = 1 AND next_run <= ? ORDER BY next_run`, now.Unix())
+}
+
+func (s *scheduleStore) List(chat string) ([]Schedule, error) {
+	if chat == "" {
+		return s.query(`WHERE active = 1 ORDER BY next_run`)
+	}
+	return s.query(`WHERE active = 1 AND chat = ? ORDER BY next_run`, chat)
+}
+
+func (s *scheduleStore) query(clause string, args ...interface{}) ([]Schedule, error) {
+	rows, err := s.db.Query(`
+		SELECT id, chat, cron, timezone, type, content, caption, next_run, last_run, active, created_by, created_at
+		FROM awara_schedules `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("schedule query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []Schedule
+	for rows.Next() {
+		var s Schedule
+		if err := rows.Scan(&s.ID, &s.Chat, &s.Cron, &s.Timezone, &s.Type, &s.Content, &s.Caption,
+			&s.NextRun, &s.LastRun, &s.Active, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// handleSchedule expects one of
+//
+//	SCHEDULE:add|<chat>|<when>|<timezone>|<type>|<content>[|<caption>]
+//	SCHEDULE:list[|<chat>]
+//	SCHEDULE:cancel|<id>
+//
+// where when is a local date and time ("2006-01-02 15:04") for a one-off
+// message or a cron expression, and type is text, image, video, gif or audio.
+// Group admins may only manage the schedules of the chat they ask from;
+// other chats need the owner.
+func (b *Bot) handleSchedule(meta requestMeta, msg string) {
+	parts := strings.SplitN(strings.TrimPrefix(msg, "SCHEDULE:"), "|", 7)
+
+	response := map[string]interface{}{
+		"type":      "schedule_result",
+		"requestId": meta.ID,
+	}
+	var err error
+
+	switch parts[0] {
+	case "add":
+		var sched *Schedule
+		if sched, err = b.addSchedule(meta, parts[1:]); err == nil {
+			response["schedule"] = sched
+		}
+	case "list":
+		chat := ""
+		if len(parts) > 1 {
+			chat = parts[1]
+		}
+		if !b.schedulesAnyChat(meta) {
+			chat = meta.Chat.String()
+		}
+		var schedules []Schedule
+		if schedules, err = b.schedules.List(chat); err == nil {
+			if schedules == nil {
+				schedules = []Schedule{}
+			}
+			response["schedules"] = schedules
+		}
+	case "cancel":
+		if len(parts) < 2 {
+			err = errors.New("missing schedule ID")
+			break
+		}
+		if !b.schedulesAnyChat(meta) {
+			var sched *Schedule
+			if sched, err = b.schedules.Get(parts[1]); err != nil {
+				break
+			}
+			if sched.Chat != meta.Chat.String() {
+				err = &PermissionError{Name: "SCHEDULE", Role: RoleOwner}
+				break
+			}
+		}
+		var found bool
+		if found, err = b.schedules.Cancel(parts[1]); err == nil && !found {
+			err = fmt.Errorf("schedule %s not found", parts[1])
+		}
+	default:
+		err = fmt.Errorf("unknown schedule action %q", parts[0])
+	}
+
+	response["status"] = err == nil
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	b.writeResult("SCHEDULE_RESULT", response)
+}
+
+// schedulesAnyChat reports whether meta may manage schedules outside its own
+// chat: the host process itself and owners may.
+func (b *Bot) schedulesAnyChat(meta requestMeta) bool {
+	return meta.Sender.IsEmpty() || b.isOwner(b.phoneJID(meta.Chat, meta.Sender))
+}
+
+func (b *Bot) addSchedule(meta requestMeta, fields []string) (*Schedule, error) {
+	if len(fields) < 5 {
+		return nil, errors.New("expected <chat>|<when>|<timezone>|<type>|<content>[|<caption>]")
+	}
+
+	chat, err := types.ParseJID(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat: %w", err)
+	}
+	if chat != meta.Chat && !b.schedulesAnyChat(meta) {
+		return nil, &PermissionError{Name: "SCHEDULE", Role: RoleOwner}
+	}
+
+	tz := strings.TrimSpace(fields[2])
+	if tz == "" {
+		tz = os.Getenv("SCHEDULE_TIMEZONE")
+	}
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+
+	switch MediaType(fields[3]) {
+	case "text", MediaImage, MediaVideo, MediaGIF, MediaAudio:
+	default:
+		return nil, fmt.Errorf("unsupported schedule type %q", fields[3])
+	}
+
+	now := time.Now()
+	sched := &Schedule{
+		ID:        "sch-" + strconv.FormatInt(now.UnixNano(), 36),
+		Chat:      chat.String(),
+		Timezone:  tz,
+		Type:      fields[3],
+		Content:   fields[4],
+		CreatedBy: meta.Sender.String(),
+		CreatedAt: now.Unix(),
+	}
+	if len(fields) > 5 {
+		sched.Caption = fields[5]
+	}
+
+	when := strings.TrimSpace(fields[1])
+	if at, err := time.ParseInLocation("2006-01-02 15:04", when, loc); err == nil {
+		if !at.After(now) {
+			return nil, errors.New("scheduled time is in the past")
+		}
+		sched.NextRun = at.Unix()
+	} else {
+		sched.Cron = when
+		next, err := sched.next(now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule: %w", err)
+		}
+		if next.IsZero() {
+			return nil, errors.New("cron expression never fires")
+		}
+		sched.NextRun = next.Unix()
+	}
+
+	if err := b.schedules.Add(sched); err != nil {
+		return nil, err
+	}
+	sched.Active = true
+	return sched, nil
+}
+
+// runSchedules checks for due schedules until the process exits.
+func (b *Bot) runSchedules() {
+	policy := os.Getenv("SCHEDULE_CATCH_UP")
+	if policy != catchUpOnce && policy != catchUpAll {
+		policy = catchUpSkip
+	}
+
+	for {
+		now := time.Now()
+		due, err := b.schedules.Due(now)
+		if err != nil {
+			b.Log.Errorf("Failed to load due schedules: %v", err)
+		}
+		for i := range due {
+			b.fireSchedule(&due[i], now, policy)
+		}
+		time.Sleep(scheduleTick)
+	}
+}
+
+// fireSchedule queues the sends for a due schedule and advances it. Runs
+// older than scheduleGrace were missed while offline and follow policy.
+func (b *Bot) fireSchedule(s *Schedule, now time.Time, policy string) {
+	runs := []time.Time{time.Unix(s.NextRun, 0)}
+	next, err := s.next(now)
+	if err != nil {
+		b.Log.Errorf("Schedule %s is invalid, disabling it: %v", s.ID, err)
+		b.schedules.Advance(s.ID, now, time.Time{})
+		return
+	}
+
+	if now.Sub(runs[0]) > scheduleGrace {
+		switch policy {
+		case catchUpSkip:
+			runs = nil
+		case catchUpAll:
+			if s.Cron != "" {
+				for t, _ := s.next(runs[0]); !t.IsZero() && !t.After(now) && len(runs) < scheduleMaxCatchUp; t, _ = s.next(t) {
+					runs = append(runs, t)
+				}
+			}
+		}
+		b.Log.Infof("Schedule %s missed %s, catch-up policy %s sends %d", s.ID, runs, policy, len(runs))
+	}
+
+	if err := b.schedules.Advance(s.ID, now, next); err != nil {
+		b.Log.Errorf("Failed to advance schedule %s: %v", s.ID, err)
+		return
+	}
+
+	if len(runs) == 0 {
+		b.sendEvent(BotEvent{
+			Type: "schedule_skipped",
+			Content: map[string]interface{}{
+				"scheduleId": s.ID,
+				"chat":       s.Chat,
+				"missedRun":  s.NextRun,
+			},
+		})
+		return
+	}
+
+	for _, run := range runs {
+		b.handleScheduleRun(fmt.Sprintf("SCHEDULE_RUN:%s|%d", s.ID, run.Unix()))
+	}
+}
+
+// handleScheduleRun expects SCHEDULE_RUN:<id>|<run unix time>. It is queued by
+// the schedule loop as a job, so interrupted sends are resumed like any other.
+// It is internal: the IPC reader doesn't dispatch it, only the schedule loop
+// and resumeJobs call it.
+func (b *Bot) handleScheduleRun(msg string) {
+	parts := strings.SplitN(strings.TrimPrefix(msg, "SCHEDULE_RUN:"), "|", 2)
+	if len(parts) < 2 {
+		b.Log.Errorf("Invalid SCHEDULE_RUN format")
+		return
+	}
+
+	sched, err := b.schedules.Get(parts[0])
+	if err != nil {
+		b.Log.Errorf("Schedule run failed: %v", err)
+		return
+	}
+	chat, err := types.ParseJID(sched.Chat)
+	if err != nil {
+		b.Log.Errorf("Schedule %s has an invalid chat: %v", sched.ID, err)
+		return
+	}
+
+	meta := requestMeta{ID: sched.ID + "@" + parts[1], Chat: chat}
+	b.jobs.Submit(JobScheduled, meta, msg, func() error {
+		err := b.deliverSchedule(meta, sched)
+		b.sendScheduleResult(sched, parts[1], err)
+		return err
+	}, func(err error) {
+		b.sendScheduleResult(sched, parts[1], err)
+	})
+}
+
+func (b *Bot) deliverSchedule(meta requestMeta, sched *Schedule) error {
+	var message *waProto.Message
+	var err error
+	if sched.Type == "text" {
+		message = &waProto.Message{Conversation: proto.String(strings.ReplaceAll(sched.Content, "{{NL}}", "\n"))}
+	} else {
+		message, err = b.buildBroadcastMessage(meta, MediaType(sched.Type), sched.Content, strings.ReplaceAll(sched.Caption, "{{NL}}", "\n"))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = b.send(meta.Chat, message)
+	return err
+}
+
+func (b *Bot) sendScheduleResult(sched *Schedule, run string, err error) {
+	content := map[string]interface{}{
+		"scheduleId": sched.ID,
+		"chat":       sched.Chat,
+		"run":        run,
+		"status":     err == nil,
+	}
+	eventType := "schedule_sent"
+	if err != nil {
+		eventType = "schedule_failed"
+		content["error"] = err.Error()
+	}
+	b.sendEvent(BotEvent{Type: eventType, Content: content})
+}
@@ -0,0 +1,349 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// JobKind selects the worker pool a job runs in.
+type JobKind string
+
+const (
+	JobDownload JobKind = "download"
+	JobEnhance  JobKind = "enhance"
+	JobChatbot  JobKind = "chatbot"
+	JobMedia    JobKind = "media"
+	// JobBroadcast runs one broadcast at a time; its sends are paced by the outbox.
+	JobBroadcast JobKind = "broadcast"
+	JobScheduled JobKind = "scheduled"
+	// JobPlugin runs commands of external plugins.
+	JobPlugin JobKind = "plugin"
+)
+
+type jobPriority int
+
+// Higher values are served first.
+const (
+	priorityGroup jobPriority = iota
+	priorityPrivate
+	priorityOwner
+	priorityLevels
+)
+
+func (p jobPriority) String() string {
+	switch p {
+	case priorityOwner:
+		return "owner"
+	case priorityPrivate:
+		return "private"
+	}
+	return "group"
+}
+
+var ErrQueueFull = errors.New("too many requests are queued, please try again in a moment")
+
+// requestMeta identifies who asked for a job. It comes from the optional
+// REQ:<requestId>|<chat>|<sender>|<command> envelope around a command.
+type requestMeta struct {
+	ID     string
+	Chat   types.JID
+	Sender types.JID
+}
+
+// parseRequestEnvelope strips a REQ: envelope, returning the metadata and the
+// wrapped command. Commands without an envelope get empty metadata.
+func parseRequestEnvelope(msg string) (requestMeta, string) {
+	if !strings.HasPrefix(msg, "REQ:") {
+		return requestMeta{}, msg
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(msg, "REQ:"), "|", 4)
+	if len(parts) < 4 {
+		return requestMeta{}, msg
+	}
+
+	meta := requestMeta{ID: parts[0]}
+	meta.Chat, _ = types.ParseJID(parts[1])
+	meta.Sender, _ = types.ParseJID(parts[2])
+	return meta, parts[3]
+}
+
+type job struct {
+	id       string
+	kind     JobKind
+	chat     string
+	sender   string
+	command  string
+	priority jobPriority
+	queuedAt time.Time
+	run      func() error
+	// reject answers the request when the job can't be queued, so the caller
+	// doesn't wait for a result that will never come.
+	reject func(error)
+}
+
+// jobLane holds the waiting jobs of one priority level. Chats take turns:
+// after a job is taken, its chat moves to the back of the rotation.
+type jobLane struct {
+	chats []string
+	jobs  map[string][]*job
+}
+
+func (l *jobLane) push(j *job) {
+	if len(l.jobs[j.chat]) == 0 {
+		l.chats = append(l.chats, j.chat)
+	}
+	l.jobs[j.chat] = append(l.jobs[j.chat], j)
+}
+
+func (l *jobLane) pop() *job {
+	if len(l.chats) == 0 {
+		return nil
+	}
+
+	chat := l.chats[0]
+	l.chats = l.chats[1:]
+	queue := l.jobs[chat]
+	j := queue[0]
+
+	if len(queue) > 1 {
+		l.jobs[chat] = queue[1:]
+		l.chats = append(l.chats, chat)
+	} else {
+		delete(l.jobs, chat)
+	}
+	return j
+}
+
+func (l *jobLane) size() int {
+	n := 0
+	for _, queue := range l.jobs {
+		n += len(queue)
+	}
+	return n
+}
+
+// position is the 1-based place of the last job queued for chat within this
+// lane: every chat in the rotation gets one turn per job chat has waiting.
+func (l *jobLane) position(chat string) int {
+	rounds := len(l.jobs[chat])
+	pos := 0
+	for _, c := range l.chats {
+		pos += min(len(l.jobs[c]), rounds)
+	}
+	return pos
+}
+
+type jobPool struct {
+	kind      JobKind
+	maxQueued int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	lanes  [priorityLevels]*jobLane
+	queued int
+}
+
+func newJobPool(kind JobKind, workers, maxQueued int, run func(*job)) *jobPool {
+	p := &jobPool{kind: kind, maxQueued: maxQueued}
+	p.cond = sync.NewCond(&p.mu)
+	for i := range p.lanes {
+		p.lanes[i] = &jobLane{jobs: make(map[string][]*job)}
+	}
+
+	for i := 0; i < max(1, workers); i++ {
+		go func() {
+			for {
+				run(p.next())
+			}
+		}()
+	}
+	return p
+}
+
+func (p *jobPool) push(j *job) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.maxQueued > 0 && p.queued >= p.maxQueued {
+		return 0, ErrQueueFull
+	}
+
+	p.lanes[j.priority].push(j)
+	p.queued++
+	p.cond.Signal()
+
+	position := p.lanes[j.priority].position(j.chat)
+	for prio := j.priority + 1; prio < priorityLevels; prio++ {
+		position += p.lanes[prio].size()
+	}
+	return position, nil
+}
+
+func (p *jobPool) next() *job {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		for prio := priorityLevels - 1; prio >= 0; prio-- {
+			if j := p.lanes[prio].pop(); j != nil {
+				p.queued--
+				return j
+			}
+		}
+		p.cond.Wait()
+	}
+}
+
+// jobScheduler runs long requests (downloads, enhancements, chatbot replies,
+// media downloads) in bounded per-kind worker pools instead of one goroutine
+// per request.
+type jobScheduler struct {
+	bot     *Bot
+	store   *jobStore
+	limiter *rateLimiter
+	pools   map[JobKind]*jobPool
+}
+
+func newJobScheduler(b *Bot, store *jobStore) *jobScheduler {
+	s := &jobScheduler{
+		bot:     b,
+		store:   store,
+		limiter: newRateLimiterFromEnv(),
+		pools:   make(map[JobKind]*jobPool),
+	}
+	maxQueued := envInt("JOBS_MAX_QUEUED", 50)
+
+	for kind, workers := range map[JobKind]int{
+		JobDownload:  envInt("JOBS_DOWNLOAD_WORKERS", 3),
+		JobEnhance:   envInt("JOBS_ENHANCE_WORKERS", 2),
+		JobChatbot:   envInt("JOBS_CHATBOT_WORKERS", 4),
+		JobMedia:     envInt("JOBS_MEDIA_WORKERS", 4),
+		JobBroadcast: 1,
+		JobScheduled: 2,
+		JobPlugin:    envInt("JOBS_PLUGIN_WORKERS", 2),
+	} {
+		s.pools[kind] = newJobPool(kind, workers, maxQueued, s.runJob)
+	}
+	return s
+}
+
+// Submit queues run for meta's chat and records command so the job can be
+// replayed after a restart. When the queue is full, reject is called with
+// ErrQueueFull instead.
+func (s *jobScheduler) Submit(kind JobKind, meta requestMeta, command string, run func() error, reject func(error)) {
+	j := &job{
+		id:       meta.ID,
+		kind:     kind,
+		chat:     meta.Chat.String(),
+		sender:   meta.Sender.String(),
+		command:  command,
+		priority: s.bot.requestPriority(meta),
+		queuedAt: time.Now(),
+		run:      run,
+		reject:   reject,
+	}
+	if j.id == "" {
+		j.id = fmt.Sprintf("%s-%d", kind, time.Now().UnixNano())
+	}
+
+	if j.priority != priorityOwner {
+		if err := s.limiter.Allow(kind, s.bot.costName(command), j.sender, j.chat); err != nil {
+			s.rejectRateLimited(j, err.(*RateLimitError))
+			return
+		}
+	}
+
+	if err := s.store.Queue(j); err != nil {
+		s.bot.Log.Warnf("Failed to persist %s job %s: %v", kind, j.id, err)
+	}
+
+	position, err := s.pools[kind].push(j)
+	if err != nil {
+		s.store.Finish(j.id, err)
+		s.bot.Log.Warnf("Rejected %s job %s for %s: %v", kind, j.id, j.chat, err)
+		s.bot.sendEvent(BotEvent{
+			Type: "job_rejected",
+			Content: map[string]interface{}{
+				"requestId": j.id,
+				"kind":      kind,
+				"chat":      j.chat,
+				"error":     err.Error(),
+			},
+		})
+		reject(err)
+		return
+	}
+
+	s.bot.sendEvent(BotEvent{
+		Type: "job_queued",
+		Content: map[string]interface{}{
+			"requestId": j.id,
+			"kind":      kind,
+			"chat":      j.chat,
+			"priority":  j.priority.String(),
+			"position":  position,
+		},
+	})
+}
+
+func (s *jobScheduler) rejectRateLimited(j *job, err *RateLimitError) {
+	s.bot.Log.Infof("Rate limited %s job %s for %s (%s)", j.kind, j.id, j.sender, err.Scope)
+	s.bot.sendEvent(BotEvent{
+		Type: "rate_limited",
+		Content: map[string]interface{}{
+			"requestId":  j.id,
+			"kind":       j.kind,
+			"chat":       j.chat,
+			"sender":     j.sender,
+			"scope":      err.Scope,
+			"retryAfter": int(math.Ceil(err.RetryAfter.Seconds())),
+		},
+	})
+	j.reject(err)
+}
+
+func (s *jobScheduler) runJob(j *job) {
+	if err := s.store.Start(j.id); err != nil {
+		s.bot.Log.Warnf("Failed to mark job %s running: %v", j.id, err)
+	}
+
+	s.bot.sendEvent(BotEvent{
+		Type: "job_started",
+		Content: map[string]interface{}{
+			"requestId": j.id,
+			"kind":      j.kind,
+			"chat":      j.chat,
+			"waitedMs":  time.Since(j.queuedAt).Milliseconds(),
+		},
+	})
+
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			s.bot.Log.Errorf("%s job %s panicked: %v", j.kind, j.id, r)
+			err = fmt.Errorf("internal error")
+			j.reject(err)
+		}
+		if storeErr := s.store.Finish(j.id, err); storeErr != nil {
+			s.bot.Log.Warnf("Failed to record job %s result: %v", j.id, storeErr)
+		}
+	}()
+	err = j.run()
+}
+
+func (b *Bot) requestPriority(meta requestMeta) jobPriority {
+	switch {
+	case b.isOwner(b.phoneJID(meta.Chat, meta.Sender)):
+		return priorityOwner
+	case meta.Chat.Server == types.GroupServer:
+		return priorityGroup
+	}
+	return priorityPrivate
+}
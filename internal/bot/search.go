@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const maxSearchResults = 20
+
+// handleSearch expects SEARCH:<provider>|<query>[|<limit>], where provider is
+// youtube or image.
+func (b *Bot) handleSearch(meta requestMeta, msg string) {
+	parts := strings.SplitN(msg[len("SEARCH:"):], "|", 3)
+	if len(parts) < 2 {
+		b.Log.Errorf("Invalid search format")
+		return
+	}
+
+	limit := 0
+	if len(parts) > 2 {
+		limit, _ = strconv.Atoi(strings.TrimSpace(parts[2]))
+	}
+	if limit <= 0 || limit > maxSearchResults {
+		limit = 5
+	}
+
+	b.jobs.Submit(JobDownload, meta, msg, func() error {
+		return b.handleSearchRequest(meta, parts[0], parts[1], limit)
+	}, func(err error) {
+		b.writeResult("SEARCH_RESULT", map[string]interface{}{
+			"type":      "search_result",
+			"requestId": meta.ID,
+			"status":    false,
+			"error":     err.Error(),
+		})
+	})
+}
+
+func (b *Bot) handleSearchRequest(meta requestMeta, provider, query string, limit int) error {
+	var result interface{}
+	var err error
+
+	switch provider {
+	case "youtube":
+		result, err = b.YouTubeScraper.Search(query, limit)
+	case "image":
+		result, err = b.ImageSearch.Search(query, limit)
+	default:
+		err = fmt.Errorf("unsupported search provider: %s", provider)
+	}
+
+	if err != nil {
+		b.writeResult("SEARCH_RESULT", map[string]interface{}{
+			"type":      "search_result",
+			"requestId": meta.ID,
+			"status":    false,
+			"error":     err.Error(),
+		})
+		return err
+	}
+
+	b.writeResult("SEARCH_RESULT", map[string]interface{}{
+		"type":      "search_result",
+		"requestId": meta.ID,
+		"status":    true,
+		"provider":  provider,
+		"query":     query,
+		"result":    result,
+	})
+	return nil
+}
@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// blockedPrefixes are address ranges remote fetches must never reach: loopback,
+// private, link-local (which includes the 169.254.169.254 metadata service),
+// carrier-grade NAT, multicast and other special purpose ranges.
+var blockedPrefixes = mustParsePrefixes(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// blockedHostnames resolve to internal services on common cloud providers.
+var blockedHostnames = []string{
+	"localhost",
+	"metadata",
+	"metadata.google.internal",
+	"instance-data",
+}
+
+func mustParsePrefixes(cidrs ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefixes = append(prefixes, netip.MustParsePrefix(cidr))
+	}
+	return prefixes
+}
+
+// BlockedAddressError is returned when a fetch would connect to a forbidden address.
+type BlockedAddressError struct {
+	Host   string
+	IP     string
+	Reason string
+}
+
+func (e *BlockedAddressError) Error() string {
+	if e.IP != "" {
+		return fmt.Sprintf("connection to %s (%s) blocked: %s", e.Host, e.IP, e.Reason)
+	}
+	return fmt.Sprintf("connection to %s blocked: %s", e.Host, e.Reason)
+}
+
+// addressGuard decides which hosts and addresses outgoing fetches may reach.
+type addressGuard struct {
+	allowPrefixes []netip.Prefix
+	allowHosts    map[string]bool
+}
+
+// newAddressGuardFromEnv reads FETCH_ALLOWLIST, a comma separated list of
+// hostnames, IPs or CIDRs that are reachable even if they are in a blocked range.
+func newAddressGuardFromEnv() *addressGuard {
+	g := &addressGuard{allowHosts: make(map[string]bool)}
+	for _, entry := range strings.Split(os.Getenv("FETCH_ALLOWLIST"), ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			g.allowPrefixes = append(g.allowPrefixes, prefix)
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			g.allowPrefixes = append(g.allowPrefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		} else {
+			g.allowHosts[entry] = true
+		}
+	}
+	return g
+}
+
+func (g *addressGuard) hostAllowed(host string) bool {
+	return g.allowHosts[strings.ToLower(strings.TrimSuffix(host, "."))]
+}
+
+// checkHost rejects hostnames that are internal by name, before any DNS lookup.
+func (g *addressGuard) checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if g.allowHosts[host] {
+		return nil
+	}
+	for _, blocked := range blockedHostnames {
+		if host == blocked || strings.HasSuffix(host, "."+blocked) {
+			return &BlockedAddressError{Host: host, Reason: "internal hostname"}
+		}
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.checkAddr(host, addr)
+	}
+	return nil
+}
+
+func (g *addressGuard) checkAddr(host string, addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, prefix := range g.allowPrefixes {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return &BlockedAddressError{Host: host, IP: addr.String(), Reason: "private or reserved address range " + prefix.String()}
+		}
+	}
+	return nil
+}
+
+// control runs after DNS resolution for every connection attempt, including
+// ones made while following redirects, so rebinding tricks can't slip through.
+func (g *addressGuard) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &BlockedAddressError{Host: address, Reason: "unparseable address"}
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return &BlockedAddressError{Host: host, Reason: "unresolved address"}
+	}
+	return g.checkAddr(host, addr)
+}
+
+// dialContext checks the hostname and then dials with control checking every
+// resolved address. Hostnames on the allowlist are trusted with whatever they
+// resolve to, since that is usually an internal address (minio.internal ->
+// 10.0.0.5), so they are dialed without the address check.
+func (g *addressGuard) dialContext(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	guarded := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   g.control,
+	}
+	allowed := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if g.hostAllowed(host) {
+			return allowed.DialContext(ctx, network, addr)
+		}
+		if err := g.checkHost(host); err != nil {
+			return nil, err
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+}
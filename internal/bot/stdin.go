@@ -2,17 +2,17 @@ package bot
 
 import (
 	"bufio"
-	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/moo-d/AwaraBot/internal/scraper"
+	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
@@ -52,32 +52,86 @@ func (b *Bot) startSTDINListener() {
 }
 
 func (b *Bot) processMessage(msg string) {
+	meta, msg := parseRequestEnvelope(msg)
+	if !b.checkIPCPermission(meta, msg) {
+		return
+	}
+
 	switch {
 	case strings.HasPrefix(msg, "DOWNLOAD_MEDIA:"):
-		b.handleDownloadMedia(msg)
+		b.handleDownloadMedia(meta, msg)
 	case strings.HasPrefix(msg, "ENHANCE:"):
-		b.handleEnhance(msg)
+		b.handleEnhance(meta, msg)
 	case strings.HasPrefix(msg, "CHATBOT:"):
-		b.handleChatbot(msg)
+		b.handleChatbot(meta, msg)
 	case strings.HasPrefix(msg, "DOWNLOAD:"):
-		b.handleDownloadCommand(msg)
+		b.handleDownloadCommand(meta, msg)
+	case strings.HasPrefix(msg, "COMMAND:"):
+		b.handleCommand(meta, msg)
+	case strings.HasPrefix(msg, "ROLE:"):
+		b.handleRole(meta, msg)
+	case strings.HasPrefix(msg, "PERMISSION:"):
+		b.handlePermission(meta, msg)
+	case strings.HasPrefix(msg, "PLUGINS:"):
+		b.handlePlugins()
+	case strings.HasPrefix(msg, "BROADCAST:"):
+		b.handleBroadcast(meta, msg)
+	case strings.HasPrefix(msg, "SCHEDULE:"):
+		b.handleSchedule(meta, msg)
+	case strings.HasPrefix(msg, "TAG:"):
+		b.handleTag(msg)
+	case strings.HasPrefix(msg, "JOBS:"):
+		b.handleJobsQuery(msg)
+	case strings.HasPrefix(msg, "BATCH_DOWNLOAD:"):
+		b.handleBatchDownload(meta, msg)
+	case strings.HasPrefix(msg, "FORMATS:"):
+		b.handleFormats(meta, msg)
+	case strings.HasPrefix(msg, "SEARCH:"):
+		b.handleSearch(meta, msg)
 	case strings.HasPrefix(msg, "SEND:"):
 		b.handleSendMessage(msg)
 	case strings.HasPrefix(msg, "REACT:"):
 		b.handleReaction(msg)
-	case strings.HasPrefix(msg, "SEND_URL_IMAGE:"), strings.HasPrefix(msg, "SEND_IMAGE:"):
-		b.processMediaCommand(msg, "SEND_URL_IMAGE:", MediaImage)
-		b.processMediaCommand(msg, "SEND_IMAGE:", MediaImage)
-	case strings.HasPrefix(msg, "SEND_URL_VIDEO:"), strings.HasPrefix(msg, "SEND_VIDEO:"):
-		b.processMediaCommand(msg, "SEND_URL_VIDEO:", MediaVideo)
-		b.processMediaCommand(msg, "SEND_VIDEO:", MediaVideo)
-	case strings.HasPrefix(msg, "SEND_URL_AUDIO:"), strings.HasPrefix(msg, "SEND_AUDIO:"):
-		b.processMediaCommand(msg, "SEND_URL_AUDIO:", MediaAudio)
-		b.processMediaCommand(msg, "SEND_AUDIO:", MediaAudio)
+	case strings.HasPrefix(msg, "SEND_POLL:"):
+		b.handleSendPoll(msg)
+	case strings.HasPrefix(msg, "POLL_RESULT:"):
+		b.handlePollResult(meta, msg)
+	case strings.HasPrefix(msg, "SEND_LOCATION:"):
+		b.handleSendLocation(msg)
+	case strings.HasPrefix(msg, "SEND_CONTACT:"):
+		b.handleSendContact(msg)
+	case strings.HasPrefix(msg, "SEND_ALBUM:"):
+		b.handleSendAlbum(meta, msg)
+	case strings.HasPrefix(msg, "SEND_URL_IMAGE:"):
+		b.processMediaCommand(meta, msg, "SEND_URL_IMAGE:", MediaImage)
+	case strings.HasPrefix(msg, "SEND_IMAGE:"):
+		b.processMediaCommand(meta, msg, "SEND_IMAGE:", MediaImage)
+	case strings.HasPrefix(msg, "SEND_URL_VIDEO:"):
+		b.processMediaCommand(meta, msg, "SEND_URL_VIDEO:", MediaVideo)
+	case strings.HasPrefix(msg, "SEND_VIDEO:"):
+		b.processMediaCommand(meta, msg, "SEND_VIDEO:", MediaVideo)
+	case strings.HasPrefix(msg, "SEND_URL_GIF:"):
+		b.processMediaCommand(meta, msg, "SEND_URL_GIF:", MediaGIF)
+	case strings.HasPrefix(msg, "SEND_GIF:"):
+		b.processMediaCommand(meta, msg, "SEND_GIF:", MediaGIF)
+	case strings.HasPrefix(msg, "SEND_URL_AUDIO:"):
+		b.processMediaCommand(meta, msg, "SEND_URL_AUDIO:", MediaAudio)
+	case strings.HasPrefix(msg, "SEND_AUDIO:"):
+		b.processMediaCommand(meta, msg, "SEND_AUDIO:", MediaAudio)
 	}
 }
 
-func (b *Bot) handleDownloadMedia(msg string) {
+// writeMediaData answers DOWNLOAD_MEDIA with MEDIA_DATA:[<requestId>|]<base64>
+// or MEDIA_DATA:[<requestId>|]error. The ID is only sent for REQ: requests, so
+// callers without one see the original format.
+func writeMediaData(requestID, payload string) {
+	if requestID != "" {
+		payload = requestID + "|" + payload
+	}
+	fmt.Printf("MEDIA_DATA:%sMESSAGE_END\n", payload)
+}
+
+func (b *Bot) handleDownloadMedia(meta requestMeta, msg string) {
 	parts := strings.SplitN(strings.TrimPrefix(msg, "DOWNLOAD_MEDIA:"), "|", 3)
 	if len(parts) < 3 {
 		b.Log.Errorf("Invalid DOWNLOAD_MEDIA format")
@@ -89,12 +143,12 @@ func (b *Bot) handleDownloadMedia(msg string) {
 	contextType := parts[2]
 	messageID = strings.TrimSuffix(messageID, "MESSAGE_END")
 
-	go func() {
+	b.jobs.Submit(JobMedia, meta, msg, func() error {
 		chat, err := types.ParseJID(chatJID)
 		if err != nil {
 			b.Log.Errorf("Invalid chat JID: %v", err)
-			fmt.Println("MEDIA_DATA:errorMESSAGE_END")
-			return
+			writeMediaData(meta.ID, "error")
+			return err
 		}
 
 		var msg *waE2E.Message
@@ -130,22 +184,25 @@ func (b *Bot) handleDownloadMedia(msg string) {
 			}
 		default:
 			b.Log.Errorf("Unknown context type: %s", contextType)
-			fmt.Println("MEDIA_DATA:errorMESSAGE_END")
-			return
+			writeMediaData(meta.ID, "error")
+			return fmt.Errorf("unknown context type %s", contextType)
 		}
 
 		data, err := b.Client.DownloadAny(msg)
 		if err != nil {
 			b.Log.Errorf("Download error: %v", err)
-			fmt.Println("MEDIA_DATA:errorMESSAGE_END")
-			return
+			writeMediaData(meta.ID, "error")
+			return err
 		}
 
-		fmt.Printf("MEDIA_DATA:%sMESSAGE_END\n", base64.StdEncoding.EncodeToString(data))
-	}()
+		writeMediaData(meta.ID, base64.StdEncoding.EncodeToString(data))
+		return nil
+	}, func(error) {
+		writeMediaData(meta.ID, "error")
+	})
 }
 
-func (b *Bot) handleEnhance(msg string) {
+func (b *Bot) handleEnhance(meta requestMeta, msg string) {
 	parts := strings.SplitN(msg[len("ENHANCE:"):], "|", 3)
 	if len(parts) < 3 {
 		b.Log.Errorf("Invalid enhance format")
@@ -156,10 +213,14 @@ func (b *Bot) handleEnhance(msg string) {
 	imageData := parts[1]
 	isUrl := parts[2] == "1"
 
-	go b.handleEnhanceRequest(action, imageData, isUrl)
+	b.jobs.Submit(JobEnhance, meta, msg, func() error {
+		return b.handleEnhanceRequest(meta.ID, action, imageData, isUrl)
+	}, func(err error) {
+		b.sendErrorResponse(meta.ID, err)
+	})
 }
 
-func (b *Bot) handleChatbot(msg string) {
+func (b *Bot) handleChatbot(meta requestMeta, msg string) {
 	parts := strings.SplitN(msg[len("CHATBOT:"):], "|", 4)
 	if len(parts) < 4 {
 		b.Log.Errorf("Invalid CHATBOT format")
@@ -193,17 +254,76 @@ func (b *Bot) handleChatbot(msg string) {
 		},
 	}
 
-	go b.handleGPTRequest(msgEvent, jid, prompt, model, messages)
+	if meta.Chat.IsEmpty() {
+		meta.Chat = jid
+	}
+
+	b.jobs.Submit(JobChatbot, meta, msg, func() error {
+		return b.handleGPTRequest(msgEvent, jid, prompt, model, messages)
+	}, func(err error) {
+		b.sendChatbotError(jid, err)
+	})
 }
 
-func (b *Bot) handleDownloadCommand(msg string) {
-	parts := strings.SplitN(msg[len("DOWNLOAD:"):], "|", 3)
+// handleDownloadCommand expects DOWNLOAD:<service>|<url>[|<format>[|<quality>]]
+func (b *Bot) handleDownloadCommand(meta requestMeta, msg string) {
+	parts := strings.SplitN(msg[len("DOWNLOAD:"):], "|", 4)
 	if len(parts) < 2 {
 		b.Log.Errorf("Invalid download format")
 		return
 	}
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
 
-	go b.handleDownload(parts[0], parts[1], parts[2])
+	b.jobs.Submit(JobDownload, meta, msg, func() error {
+		return b.handleDownload(meta.ID, parts[0], parts[1], parts[2], parts[3])
+	}, func(err error) {
+		b.sendErrorResponse(meta.ID, err)
+	})
+}
+
+// handleFormats expects FORMATS:<service>|<url>
+func (b *Bot) handleFormats(meta requestMeta, msg string) {
+	parts := strings.SplitN(msg[len("FORMATS:"):], "|", 2)
+	if len(parts) < 2 {
+		b.Log.Errorf("Invalid formats format")
+		return
+	}
+
+	fail := func(err error) {
+		b.writeResult("FORMATS_RESULT", map[string]interface{}{
+			"type":      "formats_result",
+			"requestId": meta.ID,
+			"status":    false,
+			"error":     err.Error(),
+		})
+	}
+
+	b.jobs.Submit(JobDownload, meta, msg, func() error {
+		var result interface{}
+		var err error
+
+		switch parts[0] {
+		case "youtube":
+			result, err = b.YouTubeScraper.Formats(parts[1])
+		default:
+			err = fmt.Errorf("formats not supported for %s", parts[0])
+		}
+
+		if err != nil {
+			fail(err)
+			return err
+		}
+
+		b.writeResult("FORMATS_RESULT", map[string]interface{}{
+			"type":      "formats_result",
+			"requestId": meta.ID,
+			"status":    true,
+			"result":    result,
+		})
+		return nil
+	}, fail)
 }
 
 func (b *Bot) handleSendMessage(msg string) {
@@ -219,12 +339,13 @@ func (b *Bot) handleSendMessage(msg string) {
 		return
 	}
 
-	_, err = b.Client.SendMessage(context.Background(), jid, &waProto.Message{
+	b.queueSend(jid, &waProto.Message{
 		Conversation: proto.String(message),
+	}, func(_ whatsmeow.SendResponse, err error) {
+		if err != nil {
+			b.Log.Errorf("Send error: %v", err)
+		}
 	})
-	if err != nil {
-		b.Log.Errorf("Send error: %v", err)
-	}
 }
 
 func (b *Bot) handleReaction(msg string) {
@@ -248,77 +369,104 @@ func (b *Bot) handleReaction(msg string) {
 		return
 	}
 
-	_, err = b.Client.SendMessage(context.Background(), jid, b.Client.BuildReaction(jid, senderjid, messageID, emoji))
-	if err != nil {
-		b.Log.Errorf("Failed to send reaction: %v", err)
-	}
+	b.queueSend(jid, b.Client.BuildReaction(jid, senderjid, messageID, emoji), func(_ whatsmeow.SendResponse, err error) {
+		if err != nil {
+			b.Log.Errorf("Failed to send reaction: %v", err)
+		}
+	})
 }
 
-func (b *Bot) handleDownload(service, url, format string) {
+func (b *Bot) handleDownload(requestID, service, url, format, quality string) error {
+	// YouTube results are cached by youtubeDownload, shared with the commands
+	cacheKey := downloadCacheKey(service, url, format, quality)
+	var cached json.RawMessage
+	if service != "youtube" && b.cache.Get("download", cacheKey, &cached) {
+		b.Log.Infof("Download cache hit for %s", cacheKey)
+		b.sendSuccessResponse(requestID, cached)
+		return nil
+	}
+
 	var result interface{}
 	var err error
 
 	switch service {
 	case "tiktok":
 		result, err = b.TikTokScraper.DownloadVideo(url)
+	case "instagram", "ig":
+		result, err = b.InstagramScraper.Download(url)
+	case "twitter", "x":
+		result, err = b.TwitterScraper.Download(url)
+	case "facebook", "fb":
+		result, err = b.FacebookScraper.Download(url)
+	case "spotify":
+		result, err = b.SpotifyScraper.Download(url, maxMediaSize)
 	case "youtube":
-		if format == "mp3" {
-			res, e := b.YouTubeScraper.Audio(url)
-			if e == nil {
-				result = map[string]interface{}{
-					"status":    true,
-					"url":       res.URL,
-					"title":     res.Title,
-					"duration":  res.Time * 60,
-					"thumbnail": res.Thumbnail,
-				}
-			}
-			err = e
-		} else {
-			res, e := b.YouTubeScraper.Video(url, "720")
-			if e == nil {
-				result = map[string]interface{}{
-					"status":   true,
-					"url":      res.URL,
-					"title":    res.Title,
-					"duration": res.Time * 60,
-				}
+		var res *scraper.DownloadResult
+		res, err = b.youtubeDownload(url, format, quality)
+		if err == nil {
+			result = map[string]interface{}{
+				"status":     true,
+				"url":        res.URL,
+				"title":      res.Title,
+				"duration":   res.Time * 60,
+				"thumbnail":  res.Thumbnail,
+				"quality":    res.Quality,
+				"size":       res.Size,
+				"downgraded": res.Downgraded,
 			}
-			err = e
 		}
+	default:
+		err = fmt.Errorf("unsupported service: %s", service)
 	}
 
 	if err != nil {
-		b.sendErrorResponse(err)
-		return
+		b.sendErrorResponse(requestID, err)
+		return err
 	}
 
-	b.sendSuccessResponse(result)
+	if service != "youtube" {
+		if err := b.cache.Put("download", cacheKey, result, b.cache.downloadTTL); err != nil {
+			b.Log.Warnf("Failed to cache download result: %v", err)
+		}
+	}
+	b.sendSuccessResponse(requestID, result)
+	return nil
 }
 
-func (b *Bot) sendSuccessResponse(result interface{}) {
-	response := map[string]interface{}{
-		"type":   "download_result",
-		"status": true,
-		"result": result,
-	}
-	jsonResponse, _ := json.Marshal(response)
-	fmt.Println("DOWNLOAD_RESULT:" + string(jsonResponse) + "MESSAGE_END")
-	os.Stdout.Sync()
+func (b *Bot) sendSuccessResponse(requestID string, result interface{}) {
+	b.writeResult("DOWNLOAD_RESULT", map[string]interface{}{
+		"type":      "download_result",
+		"requestId": requestID,
+		"status":    true,
+		"result":    result,
+	})
 }
 
-func (b *Bot) sendErrorResponse(err error) {
+func (b *Bot) sendErrorResponse(requestID string, err error) {
 	response := map[string]interface{}{
-		"type":   "download_result",
-		"status": false,
-		"error":  err.Error(),
+		"type":      "download_result",
+		"requestId": requestID,
+		"status":    false,
+		"error":     err.Error(),
+	}
+
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		for k, v := range fetchErrorDetails(err) {
+			response[k] = v
+		}
 	}
+	b.writeResult("DOWNLOAD_RESULT", response)
+}
+
+// writeResult prints a <prefix>:<json>MESSAGE_END line that the TS side waits on with handleResponse.
+func (b *Bot) writeResult(prefix string, response map[string]interface{}) {
 	jsonResponse, _ := json.Marshal(response)
-	fmt.Println("DOWNLOAD_RESULT:" + string(jsonResponse) + "MESSAGE_END")
+	fmt.Println(prefix + ":" + string(jsonResponse) + "MESSAGE_END")
 	os.Stdout.Sync()
 }
 
-func (b *Bot) handleGPTRequest(evt *events.Message, jid types.JID, prompt, model string, messages []scraper.Message) {
+func (b *Bot) handleGPTRequest(evt *events.Message, jid types.JID, prompt, model string, messages []scraper.Message) error {
 	if len(messages) == 0 || messages[0].Role != "system" {
 		messages = append([]scraper.Message{
 			{
@@ -331,14 +479,8 @@ func (b *Bot) handleGPTRequest(evt *events.Message, jid types.JID, prompt, model
 	result, err := b.GPTScraper.Chat(prompt, messages, model)
 	if err != nil {
 		b.Log.Errorf("GPT error: %v", err)
-		b.sendEvent(BotEvent{
-			Type: "chatbot_error",
-			Content: map[string]interface{}{
-				"chat":  jid,
-				"error": err.Error(),
-			},
-		})
-		return
+		b.sendChatbotError(jid, err)
+		return err
 	}
 
 	var jsonResponse struct {
@@ -362,37 +504,42 @@ func (b *Bot) handleGPTRequest(evt *events.Message, jid types.JID, prompt, model
 			"caption":   jsonResponse.Caption,
 		},
 	})
+	return nil
 }
 
-func (b *Bot) handleEnhanceRequest(action, imageData string, isUrl bool) {
+func (b *Bot) sendChatbotError(jid types.JID, err error) {
+	b.sendEvent(BotEvent{
+		Type: "chatbot_error",
+		Content: map[string]interface{}{
+			"chat":  jid,
+			"error": err.Error(),
+		},
+	})
+}
+
+func (b *Bot) handleEnhanceRequest(requestID, action, imageData string, isUrl bool) error {
 	var imgBytes []byte
 	var err error
 
 	if isUrl {
-		resp, err := http.Get(imageData)
-		if err != nil {
-			b.sendErrorResponse(err)
-			return
-		}
-		defer resp.Body.Close()
-
-		imgBytes, err = io.ReadAll(resp.Body)
+		imgBytes, _, err = b.fetcher.Fetch(imageData, MediaImage)
 	} else {
 		imgBytes, err = base64.StdEncoding.DecodeString(imageData)
 	}
 
 	if err != nil {
-		b.sendErrorResponse(err)
-		return
+		b.sendErrorResponse(requestID, err)
+		return err
 	}
 
 	enhanced, err := b.VyroScraper.EnhanceImage(imgBytes, action)
 	if err != nil {
-		b.sendErrorResponse(err)
-		return
+		b.sendErrorResponse(requestID, err)
+		return err
 	}
 
-	b.sendSuccessResponse(map[string]interface{}{
+	b.sendSuccessResponse(requestID, map[string]interface{}{
 		"url": fmt.Sprintf("data:image/jpeg;base64,%s", base64.StdEncoding.EncodeToString(enhanced)),
 	})
+	return nil
 }
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// cachedUpload is the part of an UploadResponse needed to reference media
+// that is already on the WhatsApp servers.
+type cachedUpload struct {
+	URL           string `json:"url"`
+	DirectPath    string `json:"directPath"`
+	MediaKey      []byte `json:"mediaKey"`
+	FileEncSHA256 []byte `json:"fileEncSha256"`
+	FileSHA256    []byte `json:"fileSha256"`
+	FileLength    uint64 `json:"fileLength"`
+}
+
+// uploadCacheKey keys uploads by the plaintext SHA256 and the media type,
+// since the encryption keys depend on both.
+func uploadCacheKey(data []byte, mediaType whatsmeow.MediaType) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) + ":" + string(mediaType)
+}
+
+// upload returns an UploadResponse for data, reusing an earlier upload of the
+// same content while it is cached. Sends never report media the servers have
+// purged (recipients just fail to download it), so UPLOAD_CACHE_TTL has to
+// stay well below the servers' retention.
+func (b *Bot) upload(data []byte, mediaType whatsmeow.MediaType, progress *progressTracker) (whatsmeow.UploadResponse, error) {
+	key := uploadCacheKey(data, mediaType)
+
+	var cached cachedUpload
+	if b.cache.Get("upload", key, &cached) {
+		progress.Complete(int64(len(data)))
+		return whatsmeow.UploadResponse{
+			URL:           cached.URL,
+			DirectPath:    cached.DirectPath,
+			MediaKey:      cached.MediaKey,
+			FileEncSHA256: cached.FileEncSHA256,
+			FileSHA256:    cached.FileSHA256,
+			FileLength:    cached.FileLength,
+		}, nil
+	}
+
+	uploaded, err := b.rawUpload(data, mediaType, progress)
+	if err != nil {
+		return uploaded, fmt.Errorf("upload failed: %v", err)
+	}
+
+	err = b.cache.Put("upload", key, cachedUpload{
+		URL:           uploaded.URL,
+		DirectPath:    uploaded.DirectPath,
+		MediaKey:      uploaded.MediaKey,
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    uploaded.FileLength,
+	}, b.cache.uploadTTL)
+	if err != nil {
+		b.Log.Warnf("Failed to cache upload: %v", err)
+	}
+	return uploaded, nil
+}
+
+// rawUpload uploads data, streaming it through a temp file when progress is
+// tracked so the bytes sent to the media server can be counted.
+func (b *Bot) rawUpload(data []byte, mediaType whatsmeow.MediaType, progress *progressTracker) (whatsmeow.UploadResponse, error) {
+	if progress == nil {
+		return b.Client.Upload(context.Background(), data, mediaType)
+	}
+
+	tmpFile, err := os.CreateTemp("", "awara-upload-*")
+	if err != nil {
+		return whatsmeow.UploadResponse{}, err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	uploaded, err := b.Client.UploadReader(
+		context.Background(),
+		bytes.NewReader(data),
+		&uploadProgressFile{f: tmpFile, progress: progress},
+		mediaType,
+	)
+	if err == nil {
+		progress.Finish()
+	}
+	return uploaded, err
+}
@@ -0,0 +1,201 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// facebookHosts are the domains Download accepts, along with their subdomains.
+var facebookHosts = []string{"facebook.com", "fb.watch", "fb.com"}
+
+var (
+	facebookHDRe       = regexp.MustCompile(`"(?:browser_native_hd_url|playable_url_quality_hd)":"([^"]+)"`)
+	facebookSDRe       = regexp.MustCompile(`"(?:browser_native_sd_url|playable_url)":"([^"]+)"`)
+	facebookDurationRe = regexp.MustCompile(`"playable_duration_in_ms":(\d+)`)
+	facebookVideoIDRe  = regexp.MustCompile(`"video_id":"(\d+)"`)
+	facebookWidthRe    = regexp.MustCompile(`"original_width":(\d+)`)
+	facebookHeightRe   = regexp.MustCompile(`"original_height":(\d+)`)
+	facebookManifestRe = regexp.MustCompile(`"dash_manifest":"((?:[^"\\]|\\.)*)"`)
+	dashRepresentation = regexp.MustCompile(`<Representation\b[^>]*>`)
+	xmlAttrRe          = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	ogTitleRe          = regexp.MustCompile(`<meta property="og:title" content="([^"]*)"`)
+	ogImageRe          = regexp.MustCompile(`<meta property="og:image" content="([^"]*)"`)
+)
+
+type FacebookScraper struct {
+	client *http.Client
+}
+
+func NewFacebookScraper() *FacebookScraper {
+	return &FacebookScraper{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+type FacebookResult struct {
+	Status   bool           `json:"status"`
+	Type     string         `json:"type"`
+	ID       string         `json:"id,omitempty"`
+	Title    string         `json:"title"`
+	Cover    string         `json:"cover,omitempty"`
+	Duration int            `json:"duration,omitempty"`
+	Video    string         `json:"video"`
+	HD       string         `json:"hd,omitempty"`
+	Variants []VideoVariant `json:"variants"`
+}
+
+// unescapeJSONString decodes a string captured from inside a JSON literal in page source.
+func unescapeJSONString(s string) string {
+	var out string
+	if err := json.Unmarshal([]byte(`"`+s+`"`), &out); err != nil {
+		return strings.ReplaceAll(s, `\/`, `/`)
+	}
+	return out
+}
+
+// SetTransport replaces the transport used to fetch pages, so the caller can
+// route them through its own dial rules.
+func (f *FacebookScraper) SetTransport(transport http.RoundTripper) {
+	f.client.Transport = transport
+}
+
+// IsFacebookURL reports whether link is an http(s) URL on a Facebook domain.
+func IsFacebookURL(link string) bool {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	for _, domain := range facebookHosts {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Download resolves a public Facebook video, reel or fb.watch link to its
+// HD and SD MP4 URLs.
+func (f *FacebookScraper) Download(link string) (*FacebookResult, error) {
+	if !IsFacebookURL(link) {
+		return nil, errors.New("not a Facebook URL")
+	}
+
+	resp, err := doWithRetry(f.client, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", link, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		req.Header.Set("Sec-Fetch-Mode", "navigate")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("page request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	return parseFacebookPage(string(body))
+}
+
+func parseFacebookPage(page string) (*FacebookResult, error) {
+	result := &FacebookResult{Status: true, Type: "video"}
+
+	streams := parseDashStreams(page)
+	if m := facebookHDRe.FindStringSubmatch(page); len(m) == 2 {
+		result.HD = unescapeJSONString(m[1])
+		hd := streams["hd"]
+		hd.URL, hd.ContentType, hd.Quality = result.HD, "video/mp4", "hd"
+		if hd.Width == 0 {
+			hd.Width, hd.Height = matchInt(facebookWidthRe, page), matchInt(facebookHeightRe, page)
+		}
+		result.Variants = append(result.Variants, hd)
+	}
+	if m := facebookSDRe.FindStringSubmatch(page); len(m) == 2 {
+		if sd := unescapeJSONString(m[1]); sd != result.HD {
+			variant := streams["sd"]
+			variant.URL, variant.ContentType, variant.Quality = sd, "video/mp4", "sd"
+			result.Variants = append(result.Variants, variant)
+		}
+	}
+	if len(result.Variants) == 0 {
+		return nil, errors.New("no video found, the post may be private or not a video")
+	}
+	result.Video = result.Variants[0].URL
+
+	if m := facebookVideoIDRe.FindStringSubmatch(page); len(m) == 2 {
+		result.ID = m[1]
+	}
+	result.Duration = matchInt(facebookDurationRe, page) / 1000
+	if m := ogTitleRe.FindStringSubmatch(page); len(m) == 2 {
+		result.Title = html.UnescapeString(m[1])
+	}
+	if m := ogImageRe.FindStringSubmatch(page); len(m) == 2 {
+		result.Cover = html.UnescapeString(m[1])
+	}
+
+	return result, nil
+}
+
+// parseDashStreams reads the bitrate and resolution of the best video stream
+// per quality class ("hd", "sd") from the page's DASH manifest. The manifest
+// streams are video only, so they describe the progressive MP4s rather than
+// replace them.
+func parseDashStreams(page string) map[string]VideoVariant {
+	streams := make(map[string]VideoVariant)
+	m := facebookManifestRe.FindStringSubmatch(page)
+	if len(m) != 2 {
+		return streams
+	}
+
+	for _, tag := range dashRepresentation.FindAllString(unescapeJSONString(m[1]), -1) {
+		attrs := make(map[string]string)
+		for _, attr := range xmlAttrRe.FindAllStringSubmatch(tag, -1) {
+			attrs[attr[1]] = html.UnescapeString(attr[2])
+		}
+		if !strings.HasPrefix(attrs["mimeType"], "video/") {
+			continue
+		}
+
+		class := strings.ToLower(attrs["FBQualityClass"])
+		bitrate, _ := strconv.Atoi(attrs["bandwidth"])
+		if best, ok := streams[class]; ok && best.Bitrate >= bitrate {
+			continue
+		}
+		width, _ := strconv.Atoi(attrs["width"])
+		height, _ := strconv.Atoi(attrs["height"])
+		streams[class] = VideoVariant{Bitrate: bitrate, Width: width, Height: height}
+	}
+	return streams
+}
+
+func matchInt(re *regexp.Regexp, s string) int {
+	m := re.FindStringSubmatch(s)
+	if len(m) != 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
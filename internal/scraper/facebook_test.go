@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// newFacebookFixtureServer serves testdata/facebook pages and routes every
+// connection the scraper makes to it, whatever host the link names.
+func newFacebookFixtureServer(t *testing.T) (*FacebookScraper, *atomic.Int32) {
+	t.Helper()
+
+	requests := new(atomic.Int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fixture := map[string]string{
+			"/watch/":       "video",
+			"/reel/555":     "sd_only",
+			"/user/videos/": "private",
+		}[r.URL.Path]
+		data, err := os.ReadFile(filepath.Join("testdata", "facebook", fixture+".html"))
+		if fixture == "" || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	scraper := NewFacebookScraper()
+	scraper.SetTransport(&http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	})
+	return scraper, requests
+}
+
+func TestFacebookDownload(t *testing.T) {
+	scraper, _ := newFacebookFixtureServer(t)
+
+	res, err := scraper.Download("http://www.facebook.com/watch/?v=1234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ID != "1234567890" || res.Title != "Rendang recipe & tips" || res.Duration != 83 {
+		t.Errorf("got id %q title %q duration %d", res.ID, res.Title, res.Duration)
+	}
+	if res.Cover != "https://scontent.example/cover.jpg?a=1&b=2" {
+		t.Errorf("cover = %q", res.Cover)
+	}
+	if res.Video != res.HD || res.HD != "https://video.example/hd.mp4?tag=hd&oh=1" {
+		t.Errorf("video %q, hd %q", res.Video, res.HD)
+	}
+
+	want := []VideoVariant{
+		{URL: "https://video.example/hd.mp4?tag=hd&oh=1", ContentType: "video/mp4", Quality: "hd", Bitrate: 2100000, Width: 1280, Height: 720},
+		{URL: "https://video.example/sd.mp4?tag=sd", ContentType: "video/mp4", Quality: "sd", Bitrate: 450000, Width: 640, Height: 360},
+	}
+	if !reflect.DeepEqual(res.Variants, want) {
+		t.Errorf("variants = %+v, want %+v", res.Variants, want)
+	}
+}
+
+func TestFacebookDownloadWithoutManifest(t *testing.T) {
+	scraper, _ := newFacebookFixtureServer(t)
+
+	res, err := scraper.Download("http://fb.watch/reel/555")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.HD != "" || res.Video != "https://video.example/reel-sd.mp4" || len(res.Variants) != 1 {
+		t.Fatalf("got hd %q video %q variants %+v", res.HD, res.Video, res.Variants)
+	}
+	if v := res.Variants[0]; v.Quality != "sd" || v.Bitrate != 0 {
+		t.Errorf("variant = %+v", v)
+	}
+}
+
+func TestFacebookDownloadErrors(t *testing.T) {
+	scraper, requests := newFacebookFixtureServer(t)
+
+	if _, err := scraper.Download("http://m.facebook.com/user/videos/"); err == nil {
+		t.Error("expected an error for a page without a video")
+	}
+	if _, err := scraper.Download("http://www.facebook.com/missing"); err == nil {
+		t.Error("expected an error for a 404 page")
+	}
+
+	requests.Store(0)
+	for _, link := range []string{
+		"http://127.0.0.1:8080/?facebook.com/",
+		"http://169.254.169.254/x#fb.com/",
+		"https://facebook.com.evil.example/watch/",
+		"https://notfacebook.com/watch/",
+		"file:///etc/passwd?facebook.com/",
+	} {
+		if _, err := scraper.Download(link); err == nil {
+			t.Errorf("Download(%q) succeeded, want it rejected", link)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("rejected links made %d requests", n)
+	}
+}
+
+func TestIsFacebookURL(t *testing.T) {
+	for link, want := range map[string]bool{
+		"https://www.facebook.com/watch/?v=1": true,
+		"https://web.facebook.com/reel/1":     true,
+		"https://fb.watch/abc/":               true,
+		"https://fb.com/x":                    true,
+		"https://FACEBOOK.COM./x":             true,
+		"https://example.com/facebook.com/":   false,
+		"https://facebook.com@evil.example/":  false,
+		"ftp://facebook.com/x":                false,
+	} {
+		if got := IsFacebookURL(link); got != want {
+			t.Errorf("IsFacebookURL(%q) = %v, want %v", link, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRetries    = 3
+	defaultRetryDelay = 500 * time.Millisecond
+)
+
+// doWithRetry sends the request built by newReq, retrying network errors and
+// 5xx/429 responses with a linear backoff. newReq is called once per attempt so
+// request bodies can be rebuilt. The caller owns the returned response body.
+func doWithRetry(client *http.Client, newReq func() (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+	for attempt := 1; attempt <= defaultRetries; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, fmt.Errorf("create request failed: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		switch {
+		case err != nil:
+			lastErr = err
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			resp.Body.Close()
+			lastErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		default:
+			return resp, nil
+		}
+
+		if attempt < defaultRetries {
+			time.Sleep(time.Duration(attempt) * defaultRetryDelay)
+		}
+	}
+
+	return nil, fmt.Errorf("request failed after %d attempts: %w", defaultRetries, lastErr)
+}
@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// blockedImageStems block any word that starts with them when safe search is
+// on, which covers plurals and variants such as "nudes", "nudity" or "porno".
+var blockedImageStems = []string{
+	"nude", "nudi", "naked", "nsfw", "porn", "sex", "hentai",
+	"xxx", "lingerie", "bugil", "telanjang", "bokep",
+}
+
+// blockedImageWords only block exact words, for terms that also begin
+// harmless ones: "gore" as a stem would block "nasi goreng".
+var blockedImageWords = map[string]bool{
+	"gore": true, "gores": true, "gory": true, "gorey": true,
+}
+
+type ImageResult struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Source      string `json:"source"`
+	PageURL     string `json:"pageUrl,omitempty"`
+}
+
+// ImageProvider is a backend that ImageSearch can query. Providers return
+// full-resolution URLs where they can and don't need to deduplicate.
+type ImageProvider interface {
+	Name() string
+	SearchImages(query string, limit int) ([]ImageResult, error)
+}
+
+type ImageSearch struct {
+	providers  []ImageProvider
+	SafeSearch bool
+}
+
+func NewImageSearch(providers ...ImageProvider) *ImageSearch {
+	return &ImageSearch{
+		providers:  providers,
+		SafeSearch: true,
+	}
+}
+
+// AddProvider appends a provider that is queried when the earlier ones
+// don't return enough images.
+func (s *ImageSearch) AddProvider(provider ImageProvider) {
+	s.providers = append(s.providers, provider)
+}
+
+func isBlockedImageText(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if blockedImageWords[word] {
+			return true
+		}
+		for _, stem := range blockedImageStems {
+			if strings.HasPrefix(word, stem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// imageKey normalizes an image URL for deduplication: host and query are
+// ignored and size folders such as /236x/ or /originals/ are dropped.
+func imageKey(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) > 1 && (segments[0] == "originals" || strings.HasSuffix(segments[0], "x")) {
+		segments = segments[1:]
+	}
+	return path.Join(segments...)
+}
+
+// Search queries the providers in order until count unique images are found.
+func (s *ImageSearch) Search(query string, count int) ([]ImageResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("query cannot be empty")
+	}
+	if s.SafeSearch && isBlockedImageText(query) {
+		return nil, errors.New("query blocked by safe search")
+	}
+	if len(s.providers) == 0 {
+		return nil, errors.New("no image providers configured")
+	}
+	if count <= 0 {
+		count = 5
+	}
+
+	seen := make(map[string]bool)
+	var results []ImageResult
+	var errs []string
+
+	for _, provider := range s.providers {
+		// over-fetch since some results get filtered or deduplicated
+		images, err := provider.SearchImages(query, count*3)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+
+		for _, image := range images {
+			key := imageKey(image.URL)
+			if image.URL == "" || seen[key] {
+				continue
+			}
+			if s.SafeSearch && isBlockedImageText(image.Title+" "+image.Description) {
+				continue
+			}
+
+			seen[key] = true
+			results = append(results, image)
+			if len(results) == count {
+				return results, nil
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("image search failed: %s", strings.Join(errs, "; "))
+		}
+		return nil, fmt.Errorf("no images for %q", query)
+	}
+	return results, nil
+}
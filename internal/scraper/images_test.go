@@ -0,0 +1,28 @@
+package scraper
+
+import "testing"
+
+func TestIsBlockedImageText(t *testing.T) {
+	for text, want := range map[string]bool{
+		"nasi goreng":              false,
+		"gorengan pedas":           false,
+		"Sussex countryside":       false,
+		"Essex map":                false,
+		"sexy":                     true,
+		"nudes":                    true,
+		"nudity":                   true,
+		"porno gratis":             true,
+		"hentais":                  true,
+		"gore":                     true,
+		"gory scenes":              true,
+		"Foto BUGIL artis":         true,
+		"#nsfw art":                true,
+		"sex-ed poster":            true,
+		"anime girl (hentai)":      true,
+		"kucing lucu, anak kucing": false,
+	} {
+		if got := isBlockedImageText(text); got != want {
+			t.Errorf("isBlockedImageText(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
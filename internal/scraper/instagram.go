@@ -0,0 +1,375 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	instagramBaseURL = "https://www.instagram.com"
+	instagramAppID   = "936619743392459"
+	instagramDocID   = "8845758582119845"
+	instagramLSD     = "AVqbxe3J_YA"
+)
+
+var (
+	instagramPostRe  = regexp.MustCompile(`instagram\.com/(?:[A-Za-z0-9_.]+/)?(?:p|reels?|tv)/([A-Za-z0-9_-]+)`)
+	instagramStoryRe = regexp.MustCompile(`instagram\.com/stories/([A-Za-z0-9_.]+)(?:/(\d+))?`)
+
+	ErrInstagramStory = errors.New("this instagram story isn't public or has expired")
+)
+
+type InstagramScraper struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewInstagramScraper() *InstagramScraper {
+	return &InstagramScraper{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: instagramBaseURL,
+	}
+}
+
+type InstagramMedia struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+type InstagramResult struct {
+	Status       bool             `json:"status"`
+	Type         string           `json:"type"`
+	ID           string           `json:"id"`
+	Title        string           `json:"title"`
+	Author       string           `json:"author"`
+	AuthorName   string           `json:"authorName"`
+	AuthorAvatar string           `json:"authorAvatar,omitempty"`
+	Duration     int              `json:"duration,omitempty"`
+	Cover        string           `json:"cover,omitempty"`
+	Video        string           `json:"video,omitempty"`
+	Images       []string         `json:"images,omitempty"`
+	ImageCount   int              `json:"imageCount,omitempty"`
+	Items        []InstagramMedia `json:"items"`
+	PlayCount    int64            `json:"playCount"`
+	LikeCount    int64            `json:"likeCount"`
+	CommentCount int64            `json:"commentCount"`
+}
+
+type igMediaNode struct {
+	Typename      string  `json:"__typename"`
+	Shortcode     string  `json:"shortcode"`
+	IsVideo       bool    `json:"is_video"`
+	VideoURL      string  `json:"video_url"`
+	DisplayURL    string  `json:"display_url"`
+	VideoDuration float64 `json:"video_duration"`
+	VideoViews    int64   `json:"video_view_count"`
+	Caption       struct {
+		Edges []struct {
+			Node struct {
+				Text string `json:"text"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"edge_media_to_caption"`
+	Likes struct {
+		Count int64 `json:"count"`
+	} `json:"edge_media_preview_like"`
+	Comments struct {
+		Count int64 `json:"count"`
+	} `json:"edge_media_to_comment"`
+	Owner struct {
+		Username      string `json:"username"`
+		FullName      string `json:"full_name"`
+		ProfilePicURL string `json:"profile_pic_url"`
+	} `json:"owner"`
+	Children struct {
+		Edges []struct {
+			Node struct {
+				IsVideo    bool   `json:"is_video"`
+				VideoURL   string `json:"video_url"`
+				DisplayURL string `json:"display_url"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"edge_sidecar_to_children"`
+}
+
+type igStoryItem struct {
+	PK             json.Number `json:"pk"`
+	MediaType      int         `json:"media_type"`
+	VideoDuration  float64     `json:"video_duration"`
+	ImageVersions2 struct {
+		Candidates []struct {
+			URL string `json:"url"`
+		} `json:"candidates"`
+	} `json:"image_versions2"`
+	VideoVersions []struct {
+		URL string `json:"url"`
+	} `json:"video_versions"`
+}
+
+type igProfileResponse struct {
+	Data struct {
+		User *struct {
+			ID            string `json:"id"`
+			Username      string `json:"username"`
+			FullName      string `json:"full_name"`
+			ProfilePicURL string `json:"profile_pic_url"`
+		} `json:"user"`
+	} `json:"data"`
+}
+
+type igReelsResponse struct {
+	Reels map[string]struct {
+		Items []igStoryItem `json:"items"`
+	} `json:"reels"`
+	Status string `json:"status"`
+}
+
+type igGraphQLResponse struct {
+	Data struct {
+		Media *igMediaNode `json:"xdt_shortcode_media"`
+	} `json:"data"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// ExtractInstagramShortcode returns the shortcode of a post, reel or IGTV URL.
+func ExtractInstagramShortcode(link string) (string, error) {
+	if instagramStoryRe.MatchString(link) {
+		return "", ErrInstagramStory
+	}
+
+	matches := instagramPostRe.FindStringSubmatch(link)
+	if len(matches) < 2 {
+		return "", errors.New("failed to extract shortcode from URL")
+	}
+	return matches[1], nil
+}
+
+// Download resolves a public post, reel, carousel or story to its media URLs.
+func (i *InstagramScraper) Download(link string) (*InstagramResult, error) {
+	if matches := instagramStoryRe.FindStringSubmatch(link); matches != nil {
+		return i.story(matches[1], matches[2])
+	}
+
+	shortcode, err := ExtractInstagramShortcode(link)
+	if err != nil {
+		return nil, err
+	}
+
+	variables, _ := json.Marshal(map[string]interface{}{
+		"shortcode":               shortcode,
+		"fetch_tagged_user_count": nil,
+		"hoisted_comment_id":      nil,
+		"hoisted_reply_id":        nil,
+	})
+	formData := url.Values{
+		"av":        {"0"},
+		"__d":       {"www"},
+		"__user":    {"0"},
+		"__a":       {"1"},
+		"lsd":       {instagramLSD},
+		"variables": {string(variables)},
+		"doc_id":    {instagramDocID},
+	}
+
+	resp, err := doWithRetry(i.client, func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", i.baseURL+"/graphql/query", strings.NewReader(formData.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-IG-App-ID", instagramAppID)
+		req.Header.Set("X-FB-LSD", instagramLSD)
+		req.Header.Set("X-ASBD-ID", "129477")
+		req.Header.Set("Referer", i.baseURL+"/p/"+shortcode+"/")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	var result igGraphQLResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse JSON failed: %w", err)
+	}
+
+	if result.Data.Media == nil {
+		if result.Message != "" {
+			return nil, fmt.Errorf("instagram error: %s", result.Message)
+		}
+		return nil, errors.New("post not found or private")
+	}
+
+	return buildInstagramResult(result.Data.Media), nil
+}
+
+func buildInstagramResult(media *igMediaNode) *InstagramResult {
+	result := &InstagramResult{
+		Status:       true,
+		ID:           media.Shortcode,
+		Author:       media.Owner.Username,
+		AuthorName:   media.Owner.FullName,
+		AuthorAvatar: media.Owner.ProfilePicURL,
+		Duration:     int(media.VideoDuration + 0.5),
+		Cover:        media.DisplayURL,
+		PlayCount:    media.VideoViews,
+		LikeCount:    media.Likes.Count,
+		CommentCount: media.Comments.Count,
+	}
+	if edges := media.Caption.Edges; len(edges) > 0 {
+		result.Title = edges[0].Node.Text
+	}
+
+	if children := media.Children.Edges; len(children) > 0 {
+		result.Type = "carousel"
+		for _, child := range children {
+			result.Items = append(result.Items, newInstagramMedia(child.Node.IsVideo, child.Node.VideoURL, child.Node.DisplayURL))
+		}
+	} else {
+		result.Type = "image"
+		if media.IsVideo {
+			result.Type = "video"
+		}
+		result.Items = []InstagramMedia{newInstagramMedia(media.IsVideo, media.VideoURL, media.DisplayURL)}
+	}
+
+	for _, item := range result.Items {
+		if item.Type == "video" {
+			if result.Video == "" {
+				result.Video = item.URL
+			}
+		} else {
+			result.Images = append(result.Images, item.URL)
+		}
+	}
+	result.ImageCount = len(result.Images)
+
+	return result
+}
+
+func newInstagramMedia(isVideo bool, videoURL, displayURL string) InstagramMedia {
+	if isVideo && videoURL != "" {
+		return InstagramMedia{Type: "video", URL: videoURL, Thumbnail: displayURL}
+	}
+	return InstagramMedia{Type: "image", URL: displayURL}
+}
+
+// story resolves the current stories of username through the public web API,
+// or only storyID when it is set. Instagram serves these to logged out
+// visitors for some accounts only; every other case is ErrInstagramStory.
+func (i *InstagramScraper) story(username, storyID string) (*InstagramResult, error) {
+	var profile igProfileResponse
+	if err := i.getJSON("/api/v1/users/web_profile_info/?username="+url.QueryEscape(username), &profile); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInstagramStory, err)
+	}
+	user := profile.Data.User
+	if user == nil || user.ID == "" {
+		return nil, ErrInstagramStory
+	}
+
+	var reels igReelsResponse
+	if err := i.getJSON("/api/v1/feed/reels_media/?reel_ids="+url.QueryEscape(user.ID), &reels); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInstagramStory, err)
+	}
+
+	result := &InstagramResult{
+		Status:       true,
+		Type:         "story",
+		ID:           storyID,
+		Author:       user.Username,
+		AuthorName:   user.FullName,
+		AuthorAvatar: user.ProfilePicURL,
+	}
+	if result.ID == "" {
+		result.ID = user.ID
+	}
+
+	for _, item := range reels.Reels[user.ID].Items {
+		if storyID != "" && item.PK.String() != storyID {
+			continue
+		}
+
+		var display, video string
+		if candidates := item.ImageVersions2.Candidates; len(candidates) > 0 {
+			display = candidates[0].URL
+		}
+		if len(item.VideoVersions) > 0 {
+			video = item.VideoVersions[0].URL
+		}
+		media := newInstagramMedia(item.MediaType == 2, video, display)
+		if media.URL == "" {
+			continue
+		}
+
+		result.Items = append(result.Items, media)
+		if media.Type == "video" {
+			if result.Video == "" {
+				result.Video = media.URL
+				result.Duration = int(item.VideoDuration + 0.5)
+			}
+		} else {
+			result.Images = append(result.Images, media.URL)
+		}
+		if result.Cover == "" {
+			result.Cover = display
+		}
+	}
+	if len(result.Items) == 0 {
+		return nil, ErrInstagramStory
+	}
+	result.ImageCount = len(result.Images)
+
+	return result, nil
+}
+
+func (i *InstagramScraper) getJSON(path string, v interface{}) error {
+	resp, err := doWithRetry(i.client, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", i.baseURL+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-IG-App-ID", instagramAppID)
+		req.Header.Set("X-ASBD-ID", "129477")
+		req.Header.Set("Referer", i.baseURL+"/")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("read response failed: %w", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("parse JSON failed: %w", err)
+	}
+	return nil
+}
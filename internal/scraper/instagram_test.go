@@ -0,0 +1,182 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newInstagramFixtureServer stands in for instagram.com, answering GraphQL
+// queries with testdata/instagram/<shortcode>.json and the story endpoints
+// with the profile and reels_media fixtures. Only the "awara" account has
+// public stories.
+func newInstagramFixtureServer(t *testing.T) *InstagramScraper {
+	t.Helper()
+
+	serveFixture := func(w http.ResponseWriter, name string) {
+		data, err := os.ReadFile(filepath.Join("testdata", "instagram", name+".json"))
+		if err != nil {
+			http.NotFound(w, nil)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql/query", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-IG-App-ID") != instagramAppID {
+			http.Error(w, "missing app id", http.StatusBadRequest)
+			return
+		}
+		var variables struct {
+			Shortcode string `json:"shortcode"`
+		}
+		json.Unmarshal([]byte(r.FormValue("variables")), &variables)
+		switch variables.Shortcode {
+		case "C1reel":
+			serveFixture(w, "reel")
+		case "C1post":
+			serveFixture(w, "post")
+		case "C1carousel":
+			serveFixture(w, "carousel")
+		default:
+			serveFixture(w, "missing")
+		}
+	})
+	mux.HandleFunc("/api/v1/users/web_profile_info/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("username") != "awara" {
+			http.Error(w, `{"message":"login_required","status":"fail"}`, http.StatusUnauthorized)
+			return
+		}
+		serveFixture(w, "profile")
+	})
+	mux.HandleFunc("/api/v1/feed/reels_media/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("reel_ids") != "4242" {
+			http.Error(w, `{"message":"login_required","status":"fail"}`, http.StatusUnauthorized)
+			return
+		}
+		serveFixture(w, "reels_media")
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	scraper := NewInstagramScraper()
+	scraper.baseURL = server.URL
+	return scraper
+}
+
+func TestInstagramDownload(t *testing.T) {
+	scraper := newInstagramFixtureServer(t)
+
+	tests := []struct {
+		name     string
+		link     string
+		kind     string
+		title    string
+		duration int
+		items    []InstagramMedia
+	}{
+		{
+			name:     "reel",
+			link:     "https://www.instagram.com/reel/C1reel/?igsh=abc",
+			kind:     "video",
+			title:    "Sunset reel",
+			duration: 15,
+			items: []InstagramMedia{
+				{Type: "video", URL: "https://cdn.example/reel.mp4", Thumbnail: "https://cdn.example/reel.jpg"},
+			},
+		},
+		{
+			name: "post",
+			link: "https://instagram.com/awara/p/C1post/",
+			kind: "image",
+			items: []InstagramMedia{
+				{Type: "image", URL: "https://cdn.example/post.jpg"},
+			},
+		},
+		{
+			name:  "carousel",
+			link:  "https://www.instagram.com/p/C1carousel/",
+			kind:  "carousel",
+			title: "Trip",
+			items: []InstagramMedia{
+				{Type: "image", URL: "https://cdn.example/c1.jpg"},
+				{Type: "video", URL: "https://cdn.example/c2.mp4", Thumbnail: "https://cdn.example/c2.jpg"},
+				{Type: "image", URL: "https://cdn.example/c3.jpg"},
+			},
+		},
+		{
+			name:     "story",
+			link:     "https://www.instagram.com/stories/awara/3300000000000000002/",
+			kind:     "story",
+			duration: 9,
+			items: []InstagramMedia{
+				{Type: "video", URL: "https://cdn.example/s2.mp4", Thumbnail: "https://cdn.example/s2.jpg"},
+			},
+		},
+		{
+			name: "all stories",
+			link: "https://www.instagram.com/stories/awara/",
+			kind: "story",
+			items: []InstagramMedia{
+				{Type: "image", URL: "https://cdn.example/s1.jpg"},
+				{Type: "video", URL: "https://cdn.example/s2.mp4", Thumbnail: "https://cdn.example/s2.jpg"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := scraper.Download(tt.link)
+			if err != nil {
+				t.Fatalf("Download(%q) error: %v", tt.link, err)
+			}
+			if !res.Status || res.Type != tt.kind || res.Author != "awara" {
+				t.Errorf("got status %v type %q author %q, want true %q awara", res.Status, res.Type, res.Author, tt.kind)
+			}
+			if res.Title != tt.title {
+				t.Errorf("title = %q, want %q", res.Title, tt.title)
+			}
+			if tt.duration != 0 && res.Duration != tt.duration {
+				t.Errorf("duration = %d, want %d", res.Duration, tt.duration)
+			}
+			if !reflect.DeepEqual(res.Items, tt.items) {
+				t.Errorf("items = %+v, want %+v", res.Items, tt.items)
+			}
+		})
+	}
+}
+
+func TestInstagramCarouselSummary(t *testing.T) {
+	res, err := newInstagramFixtureServer(t).Download("https://www.instagram.com/p/C1carousel/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Video != "https://cdn.example/c2.mp4" || res.ImageCount != 2 {
+		t.Errorf("video %q, imageCount %d", res.Video, res.ImageCount)
+	}
+}
+
+func TestInstagramDownloadErrors(t *testing.T) {
+	scraper := newInstagramFixtureServer(t)
+
+	if _, err := scraper.Download("https://www.instagram.com/p/C1gone/"); err == nil {
+		t.Error("expected an error for a missing post")
+	}
+	if _, err := scraper.Download("https://www.instagram.com/stories/private.user/1/"); !errors.Is(err, ErrInstagramStory) {
+		t.Errorf("private story error = %v, want ErrInstagramStory", err)
+	}
+	if _, err := scraper.Download("https://www.instagram.com/stories/awara/999/"); !errors.Is(err, ErrInstagramStory) {
+		t.Errorf("expired story error = %v, want ErrInstagramStory", err)
+	}
+	if _, err := scraper.Download("https://example.com/nothing"); err == nil {
+		t.Error("expected an error for a non-instagram link")
+	}
+}
@@ -0,0 +1,143 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const pinterestBaseURL = "https://www.pinterest.com"
+
+type PinterestScraper struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewPinterestScraper() *PinterestScraper {
+	return &PinterestScraper{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: pinterestBaseURL,
+	}
+}
+
+type pinterestImage struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type pinterestSearchResponse struct {
+	ResourceResponse struct {
+		Data struct {
+			Results []struct {
+				ID          string                    `json:"id"`
+				Title       string                    `json:"title"`
+				GridTitle   string                    `json:"grid_title"`
+				Description string                    `json:"description"`
+				Images      map[string]pinterestImage `json:"images"`
+			} `json:"results"`
+		} `json:"data"`
+		Message string `json:"message"`
+	} `json:"resource_response"`
+}
+
+func (p *PinterestScraper) Name() string {
+	return "pinterest"
+}
+
+// SearchImages returns pins matching query, using the original upload when
+// Pinterest exposes it and the largest thumbnail otherwise.
+func (p *PinterestScraper) SearchImages(query string, limit int) ([]ImageResult, error) {
+	if limit <= 0 || limit > 50 {
+		limit = 25
+	}
+
+	sourceURL := "/search/pins/?q=" + url.QueryEscape(query)
+	data, _ := json.Marshal(map[string]interface{}{
+		"options": map[string]interface{}{
+			"query":     query,
+			"scope":     "pins",
+			"page_size": limit,
+		},
+		"context": map[string]interface{}{},
+	})
+	params := url.Values{
+		"source_url": {sourceURL},
+		"data":       {string(data)},
+	}
+
+	resp, err := doWithRetry(p.client, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", p.baseURL+"/resource/BaseSearchResource/get/?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json, text/javascript, */*; q=0.01")
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+		req.Header.Set("X-Pinterest-Source-Url", sourceURL)
+		req.Header.Set("Referer", p.baseURL+"/")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	var result pinterestSearchResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse JSON failed: %w", err)
+	}
+
+	var images []ImageResult
+	for _, pin := range result.ResourceResponse.Data.Results {
+		image, ok := pin.Images["orig"]
+		if !ok {
+			image = largestPinterestImage(pin.Images)
+		}
+		if image.URL == "" {
+			continue
+		}
+
+		title := pin.Title
+		if title == "" {
+			title = pin.GridTitle
+		}
+
+		images = append(images, ImageResult{
+			URL:         image.URL,
+			Title:       strings.TrimSpace(title),
+			Description: strings.TrimSpace(pin.Description),
+			Width:       image.Width,
+			Height:      image.Height,
+			Source:      p.Name(),
+			PageURL:     pinterestBaseURL + "/pin/" + pin.ID + "/",
+		})
+	}
+
+	return images, nil
+}
+
+func largestPinterestImage(images map[string]pinterestImage) pinterestImage {
+	var best pinterestImage
+	for _, image := range images {
+		if image.Width*image.Height > best.Width*best.Height {
+			best = image
+		}
+	}
+	return best
+}
@@ -0,0 +1,202 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	spotifyTrackURL = "https://open.spotify.com/track/"
+	// seconds of duration difference still treated as the same recording
+	spotifyMatchSlack = 15
+)
+
+var (
+	spotifyTrackIDRe = regexp.MustCompile(`open\.spotify\.com/(?:intl-[a-z]+/)?track/([A-Za-z0-9]{22})`)
+	ogDescriptionRe  = regexp.MustCompile(`<meta property="og:description" content="([^"]*)"`)
+	musicDurationRe  = regexp.MustCompile(`<meta name="music:duration" content="(\d+)"`)
+)
+
+type SpotifyScraper struct {
+	client  *http.Client
+	youtube *YouTubeScraper
+}
+
+func NewSpotifyScraper(youtube *YouTubeScraper) *SpotifyScraper {
+	return &SpotifyScraper{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		youtube: youtube,
+	}
+}
+
+type SpotifyTrack struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Artists  []string `json:"artists"`
+	Album    string   `json:"album"`
+	Cover    string   `json:"cover"`
+	Duration int      `json:"duration"`
+}
+
+type SpotifyResult struct {
+	Status     bool     `json:"status"`
+	Title      string   `json:"title"`
+	Artists    []string `json:"artists"`
+	Album      string   `json:"album"`
+	Cover      string   `json:"cover"`
+	Thumbnail  string   `json:"thumbnail"`
+	Duration   int      `json:"duration"`
+	URL        string   `json:"url"`
+	YouTubeURL string   `json:"youtubeUrl"`
+	Quality    string   `json:"quality,omitempty"`
+	Size       int64    `json:"size,omitempty"`
+}
+
+// ExtractSpotifyTrackID returns the 22 character track ID of an open.spotify.com link.
+func ExtractSpotifyTrackID(link string) (string, error) {
+	matches := spotifyTrackIDRe.FindStringSubmatch(link)
+	if len(matches) < 2 {
+		return "", errors.New("failed to extract track ID from URL")
+	}
+	return matches[1], nil
+}
+
+// Track reads the public metadata Spotify embeds in the track page.
+func (s *SpotifyScraper) Track(link string) (*SpotifyTrack, error) {
+	trackID, err := ExtractSpotifyTrackID(link)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doWithRetry(s.client, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", spotifyTrackURL+trackID, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("page request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	return parseSpotifyPage(trackID, string(body))
+}
+
+func parseSpotifyPage(trackID, page string) (*SpotifyTrack, error) {
+	track := &SpotifyTrack{ID: trackID}
+
+	if m := ogTitleRe.FindStringSubmatch(page); len(m) == 2 {
+		track.Title = html.UnescapeString(m[1])
+	}
+	if track.Title == "" {
+		return nil, errors.New("track metadata not found")
+	}
+
+	// og:description reads "Artist, Artist · Album · Song · 2020"
+	if m := ogDescriptionRe.FindStringSubmatch(page); len(m) == 2 {
+		fields := strings.Split(html.UnescapeString(m[1]), " · ")
+		if len(fields) > 0 {
+			for _, artist := range strings.Split(fields[0], ", ") {
+				if artist = strings.TrimSpace(artist); artist != "" {
+					track.Artists = append(track.Artists, artist)
+				}
+			}
+		}
+		if len(fields) > 1 {
+			track.Album = strings.TrimSpace(fields[1])
+		}
+	}
+
+	if m := ogImageRe.FindStringSubmatch(page); len(m) == 2 {
+		track.Cover = html.UnescapeString(m[1])
+	}
+	if m := musicDurationRe.FindStringSubmatch(page); len(m) == 2 {
+		track.Duration, _ = strconv.Atoi(m[1])
+	}
+
+	return track, nil
+}
+
+// matchYouTube searches YouTube for the track and picks the first result whose
+// length is within spotifyMatchSlack of the track, falling back to the top hit.
+func (s *SpotifyScraper) matchYouTube(track *SpotifyTrack) (*SearchResult, error) {
+	query := track.Title
+	if len(track.Artists) > 0 {
+		query = strings.Join(track.Artists, " ") + " " + track.Title
+	}
+
+	results, err := s.youtube.Search(query+" audio", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	if track.Duration > 0 {
+		for i := range results {
+			diff := results[i].Duration - track.Duration
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= spotifyMatchSlack {
+				return &results[i], nil
+			}
+		}
+	}
+	return &results[0], nil
+}
+
+// Download resolves a Spotify track to the audio of its best YouTube match,
+// keeping the Spotify metadata and cover art.
+func (s *SpotifyScraper) Download(link string, maxBytes int64) (*SpotifyResult, error) {
+	track, err := s.Track(link)
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := s.matchYouTube(track)
+	if err != nil {
+		return nil, fmt.Errorf("no YouTube match: %w", err)
+	}
+
+	audio, err := s.youtube.Audio(match.URL, DefaultAudioQuality, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !audio.Status {
+		return nil, errors.New(audio.Message)
+	}
+
+	return &SpotifyResult{
+		Status:     true,
+		Title:      track.Title,
+		Artists:    track.Artists,
+		Album:      track.Album,
+		Cover:      track.Cover,
+		Thumbnail:  track.Cover,
+		Duration:   track.Duration,
+		URL:        audio.URL,
+		YouTubeURL: match.URL,
+		Quality:    audio.Quality,
+		Size:       audio.Size,
+	}, nil
+}
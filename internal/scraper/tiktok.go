@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 type TikTokScraper struct {
@@ -19,16 +20,69 @@ func NewTikTokScraper() *TikTokScraper {
 	}
 }
 
+const tikWMBaseURL = "https://www.tikwm.com"
+
 type tikWMResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
 	Data struct {
-		Play   string   `json:"play"`
-		Wmplay string   `json:"wmplay"`
-		Music  string   `json:"music"`
-		Images []string `json:"images"`
+		ID           string   `json:"id"`
+		Title        string   `json:"title"`
+		Duration     int      `json:"duration"`
+		Cover        string   `json:"cover"`
+		Play         string   `json:"play"`
+		Wmplay       string   `json:"wmplay"`
+		Hdplay       string   `json:"hdplay"`
+		Size         int64    `json:"size"`
+		WmSize       int64    `json:"wm_size"`
+		HdSize       int64    `json:"hd_size"`
+		Music        string   `json:"music"`
+		PlayCount    int64    `json:"play_count"`
+		DiggCount    int64    `json:"digg_count"`
+		CommentCount int64    `json:"comment_count"`
+		ShareCount   int64    `json:"share_count"`
+		Images       []string `json:"images"`
+		Author       struct {
+			UniqueID string `json:"unique_id"`
+			Nickname string `json:"nickname"`
+			Avatar   string `json:"avatar"`
+		} `json:"author"`
 	} `json:"data"`
 }
 
-func (t *TikTokScraper) DownloadVideo(tiktokURL string) (map[string]interface{}, error) {
+type TikTokResult struct {
+	Status       bool     `json:"status"`
+	Type         string   `json:"type"`
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	Author       string   `json:"author"`
+	AuthorName   string   `json:"authorName"`
+	AuthorAvatar string   `json:"authorAvatar,omitempty"`
+	Duration     int      `json:"duration"`
+	Cover        string   `json:"cover,omitempty"`
+	Video        string   `json:"video,omitempty"`
+	VideoSize    int64    `json:"videoSize,omitempty"`
+	HD           string   `json:"hd,omitempty"`
+	HDSize       int64    `json:"hdSize,omitempty"`
+	WM           string   `json:"wm,omitempty"`
+	WMSize       int64    `json:"wmSize,omitempty"`
+	Music        string   `json:"music,omitempty"`
+	Images       []string `json:"images,omitempty"`
+	ImageCount   int      `json:"imageCount,omitempty"`
+	PlayCount    int64    `json:"playCount"`
+	LikeCount    int64    `json:"likeCount"`
+	CommentCount int64    `json:"commentCount"`
+	ShareCount   int64    `json:"shareCount"`
+}
+
+func tikWMURL(path string) string {
+	if path == "" || strings.HasPrefix(path, "http") {
+		return path
+	}
+	return tikWMBaseURL + path
+}
+
+func (t *TikTokScraper) DownloadVideo(tiktokURL string) (*TikTokResult, error) {
 	formData := url.Values{
 		"url":    {tiktokURL},
 		"count":  {"12"},
@@ -39,7 +93,7 @@ func (t *TikTokScraper) DownloadVideo(tiktokURL string) (map[string]interface{},
 
 	req, err := http.NewRequest(
 		"POST",
-		"https://www.tikwm.com/api/",
+		tikWMBaseURL+"/api/",
 		bytes.NewBufferString(formData.Encode()),
 	)
 	if err != nil {
@@ -72,15 +126,41 @@ func (t *TikTokScraper) DownloadVideo(tiktokURL string) (map[string]interface{},
 		return nil, fmt.Errorf("parse JSON failed: %w", err)
 	}
 
-	response := make(map[string]interface{}, 4)
-	response["status"] = true
-	response["wm"] = "https://www.tikwm.com" + result.Data.Wmplay
-	response["music"] = "https://www.tikwm.com" + result.Data.Music
-	response["video"] = "https://www.tikwm.com" + result.Data.Play
+	if result.Code != 0 {
+		return nil, fmt.Errorf("tikwm error: %s", result.Msg)
+	}
 
-	if len(result.Data.Images) > 0 {
-		response["images"] = result.Data.Images
+	data := result.Data
+	response := &TikTokResult{
+		Status:       true,
+		Type:         "video",
+		ID:           data.ID,
+		Title:        data.Title,
+		Author:       data.Author.UniqueID,
+		AuthorName:   data.Author.Nickname,
+		AuthorAvatar: tikWMURL(data.Author.Avatar),
+		Duration:     data.Duration,
+		Cover:        tikWMURL(data.Cover),
+		Music:        tikWMURL(data.Music),
+		PlayCount:    data.PlayCount,
+		LikeCount:    data.DiggCount,
+		CommentCount: data.CommentCount,
+		ShareCount:   data.ShareCount,
 	}
 
+	if len(data.Images) > 0 {
+		response.Type = "slideshow"
+		response.Images = data.Images
+		response.ImageCount = len(data.Images)
+		return response, nil
+	}
+
+	response.Video = tikWMURL(data.Play)
+	response.VideoSize = data.Size
+	response.HD = tikWMURL(data.Hdplay)
+	response.HDSize = data.HdSize
+	response.WM = tikWMURL(data.Wmplay)
+	response.WMSize = data.WmSize
+
 	return response, nil
 }
@@ -0,0 +1,243 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const twitterSyndicationURL = "https://cdn.syndication.twimg.com"
+
+var (
+	tweetIDRe         = regexp.MustCompile(`(?:twitter\.com|x\.com)/(?:[A-Za-z0-9_]+|i/web)/status(?:es)?/(\d+)`)
+	variantResolution = regexp.MustCompile(`/(\d+)x(\d+)/`)
+)
+
+type TwitterScraper struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewTwitterScraper() *TwitterScraper {
+	return &TwitterScraper{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL: twitterSyndicationURL,
+	}
+}
+
+// VideoVariant is one encoding of a video, as offered by X or Facebook.
+type VideoVariant struct {
+	URL         string `json:"url"`
+	ContentType string `json:"contentType,omitempty"`
+	Quality     string `json:"quality,omitempty"`
+	Bitrate     int    `json:"bitrate,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+}
+
+type TwitterMedia struct {
+	Type      string         `json:"type"`
+	URL       string         `json:"url"`
+	Thumbnail string         `json:"thumbnail,omitempty"`
+	Duration  int            `json:"duration,omitempty"`
+	Variants  []VideoVariant `json:"variants,omitempty"`
+}
+
+type TwitterResult struct {
+	Status       bool           `json:"status"`
+	Type         string         `json:"type"`
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	Author       string         `json:"author"`
+	AuthorName   string         `json:"authorName"`
+	AuthorAvatar string         `json:"authorAvatar,omitempty"`
+	Video        string         `json:"video,omitempty"`
+	IsGIF        bool           `json:"isGif,omitempty"`
+	Images       []string       `json:"images,omitempty"`
+	ImageCount   int            `json:"imageCount,omitempty"`
+	Items        []TwitterMedia `json:"items"`
+	LikeCount    int64          `json:"likeCount"`
+	CommentCount int64          `json:"commentCount"`
+}
+
+type syndicationTweet struct {
+	IDStr         string `json:"id_str"`
+	Text          string `json:"text"`
+	FavoriteCount int64  `json:"favorite_count"`
+	ReplyCount    int64  `json:"conversation_count"`
+	User          struct {
+		ScreenName      string `json:"screen_name"`
+		Name            string `json:"name"`
+		ProfileImageURL string `json:"profile_image_url_https"`
+	} `json:"user"`
+	MediaDetails []struct {
+		Type          string `json:"type"`
+		MediaURLHTTPS string `json:"media_url_https"`
+		VideoInfo     struct {
+			DurationMillis int `json:"duration_millis"`
+			Variants       []struct {
+				Bitrate     int    `json:"bitrate"`
+				ContentType string `json:"content_type"`
+				URL         string `json:"url"`
+			} `json:"variants"`
+		} `json:"video_info"`
+	} `json:"mediaDetails"`
+}
+
+// ExtractTweetID returns the status ID of a twitter.com or x.com URL.
+func ExtractTweetID(link string) (string, error) {
+	matches := tweetIDRe.FindStringSubmatch(link)
+	if len(matches) < 2 {
+		return "", errors.New("failed to extract tweet ID from URL")
+	}
+	return matches[1], nil
+}
+
+// syndicationToken reproduces the token the embed widget derives from the
+// tweet ID: (id / 1e15 * PI) written in base 36 without zeros or the dot.
+func syndicationToken(id string) string {
+	n, err := strconv.ParseFloat(id, 64)
+	if err != nil {
+		return "0"
+	}
+	value := n / 1e15 * math.Pi
+
+	const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+	intPart := int64(value)
+	frac := value - float64(intPart)
+
+	token := strconv.FormatInt(intPart, 36)
+	for i := 0; i < 12 && frac > 0; i++ {
+		frac *= 36
+		d := int(frac)
+		token += string(digits[d])
+		frac -= float64(d)
+	}
+	return strings.ReplaceAll(token, "0", "")
+}
+
+// Download resolves a tweet to its photos and videos. Videos list every MP4
+// variant, best bitrate first; animated GIFs are reported as type "gif" and
+// come as a single MP4 meant to be sent with GIF playback.
+func (t *TwitterScraper) Download(link string) (*TwitterResult, error) {
+	tweetID, err := ExtractTweetID(link)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doWithRetry(t.client, func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/tweet-result?id=%s&lang=en&token=%s", t.baseURL, tweetID, syndicationToken(tweetID)), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.New("tweet not found or protected")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response failed: %w", err)
+	}
+
+	var tweet syndicationTweet
+	if err := json.Unmarshal(body, &tweet); err != nil {
+		return nil, fmt.Errorf("parse JSON failed: %w", err)
+	}
+	if tweet.IDStr == "" {
+		return nil, errors.New("tweet not found or protected")
+	}
+	if len(tweet.MediaDetails) == 0 {
+		return nil, errors.New("tweet has no media")
+	}
+
+	return buildTwitterResult(&tweet), nil
+}
+
+func buildTwitterResult(tweet *syndicationTweet) *TwitterResult {
+	result := &TwitterResult{
+		Status:       true,
+		ID:           tweet.IDStr,
+		Title:        tweet.Text,
+		Author:       tweet.User.ScreenName,
+		AuthorName:   tweet.User.Name,
+		AuthorAvatar: tweet.User.ProfileImageURL,
+		LikeCount:    tweet.FavoriteCount,
+		CommentCount: tweet.ReplyCount,
+	}
+
+	for _, media := range tweet.MediaDetails {
+		if media.Type == "photo" {
+			url := media.MediaURLHTTPS + "?name=orig"
+			result.Items = append(result.Items, TwitterMedia{Type: "image", URL: url})
+			result.Images = append(result.Images, url)
+			continue
+		}
+
+		item := TwitterMedia{
+			Type:      "video",
+			Thumbnail: media.MediaURLHTTPS,
+			Duration:  media.VideoInfo.DurationMillis / 1000,
+		}
+		if media.Type == "animated_gif" {
+			item.Type = "gif"
+		}
+
+		for _, v := range media.VideoInfo.Variants {
+			if v.ContentType != "video/mp4" {
+				continue
+			}
+			variant := VideoVariant{URL: v.URL, ContentType: v.ContentType, Bitrate: v.Bitrate}
+			if m := variantResolution.FindStringSubmatch(v.URL); len(m) == 3 {
+				variant.Width, _ = strconv.Atoi(m[1])
+				variant.Height, _ = strconv.Atoi(m[2])
+				variant.Quality = m[2] + "p"
+			}
+			item.Variants = append(item.Variants, variant)
+		}
+		sort.SliceStable(item.Variants, func(i, j int) bool {
+			return item.Variants[i].Bitrate > item.Variants[j].Bitrate
+		})
+		if len(item.Variants) == 0 {
+			continue
+		}
+
+		item.URL = item.Variants[0].URL
+		result.Items = append(result.Items, item)
+		if result.Video == "" {
+			result.Video = item.URL
+			result.IsGIF = item.Type == "gif"
+		}
+	}
+
+	result.ImageCount = len(result.Images)
+	switch {
+	case len(result.Items) > 1:
+		result.Type = "carousel"
+	case len(result.Items) == 1:
+		result.Type = result.Items[0].Type
+	}
+
+	return result
+}
@@ -0,0 +1,99 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTwitterFixtureServer stands in for the syndication API, answering
+// tweet-result requests with testdata/twitter/<fixture>.json by tweet ID.
+func newTwitterFixtureServer(t *testing.T) *TwitterScraper {
+	t.Helper()
+
+	fixtures := map[string]string{
+		"1790000000000000001": "video",
+		"1790000000000000002": "gif",
+		"1790000000000000003": "photos",
+		"1790000000000000004": "text_only",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if r.URL.Path != "/tweet-result" || r.URL.Query().Get("token") != syndicationToken(id) {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "twitter", fixtures[id]+".json"))
+		if fixtures[id] == "" || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	scraper := NewTwitterScraper()
+	scraper.baseURL = server.URL
+	return scraper
+}
+
+func TestTwitterVideo(t *testing.T) {
+	res, err := newTwitterFixtureServer(t).Download("https://x.com/awara/status/1790000000000000001?s=20")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Type != "video" || res.IsGIF || res.Author != "awara" || res.LikeCount != 4200 || res.CommentCount != 310 {
+		t.Errorf("got %+v", res)
+	}
+	want := []VideoVariant{
+		{URL: "https://video.example/vid/avc1/1280x720/high.mp4", ContentType: "video/mp4", Quality: "720p", Bitrate: 2176000, Width: 1280, Height: 720},
+		{URL: "https://video.example/vid/avc1/640x360/mid.mp4", ContentType: "video/mp4", Quality: "360p", Bitrate: 950000, Width: 640, Height: 360},
+		{URL: "https://video.example/vid/avc1/480x270/low.mp4", ContentType: "video/mp4", Quality: "270p", Bitrate: 632000, Width: 480, Height: 270},
+	}
+	if len(res.Items) != 1 || !reflect.DeepEqual(res.Items[0].Variants, want) {
+		t.Fatalf("items = %+v", res.Items)
+	}
+	if item := res.Items[0]; item.URL != want[0].URL || res.Video != want[0].URL || item.Duration != 30 || item.Thumbnail != "https://pbs.example/thumb.jpg" {
+		t.Errorf("item = %+v, video %q", item, res.Video)
+	}
+}
+
+func TestTwitterGIF(t *testing.T) {
+	res, err := newTwitterFixtureServer(t).Download("https://twitter.com/i/web/status/1790000000000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Type != "gif" || !res.IsGIF || res.Video != "https://video.example/tweet_video/mood.mp4" {
+		t.Errorf("got type %q isGif %v video %q", res.Type, res.IsGIF, res.Video)
+	}
+}
+
+func TestTwitterPhotos(t *testing.T) {
+	res, err := newTwitterFixtureServer(t).Download("https://mobile.twitter.com/awara/statuses/1790000000000000003")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://pbs.example/media/one.jpg?name=orig", "https://pbs.example/media/two.jpg?name=orig"}
+	if res.Type != "carousel" || res.ImageCount != 2 || !reflect.DeepEqual(res.Images, want) || res.Video != "" {
+		t.Errorf("got %+v", res)
+	}
+}
+
+func TestTwitterErrors(t *testing.T) {
+	scraper := newTwitterFixtureServer(t)
+
+	for _, link := range []string{
+		"https://x.com/awara/status/1790000000000000004", // no media
+		"https://x.com/awara/status/1790000000000000099", // not found
+		"https://example.com/awara/status/1",             // not a tweet
+	} {
+		if _, err := scraper.Download(link); err == nil {
+			t.Errorf("Download(%q) succeeded, want an error", link)
+		}
+	}
+}
@@ -11,9 +11,18 @@ import (
 	"io"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Qualities Video and Audio use when none is requested.
+const (
+	DefaultVideoQuality = "720"
+	DefaultAudioQuality = "128"
+)
+
 type YouTubeScraper struct {
 	client *http.Client
 }
@@ -40,6 +49,10 @@ type DownloadResult struct {
 	URL       string  `json:"url"`
 	Message   string  `json:"message,omitempty"`
 	Thumbnail string  `json:"thumbnail"`
+	Quality   string  `json:"quality,omitempty"`
+	Size      int64   `json:"size,omitempty"`
+	// Downgraded is set when a lower quality than requested was picked to stay under the size limit.
+	Downgraded bool `json:"downgraded,omitempty"`
 }
 
 func (y *YouTubeScraper) Info(url string) (*VideoInfo, error) {
@@ -166,40 +179,233 @@ func (y *YouTubeScraper) makeRequest(method, url string, data interface{}) (map[
 	return result, nil
 }
 
+var youtubeIDRe = regexp.MustCompile(`(?:youtu\.be\/|youtube\.com\/(?:watch\?v=|embed\/|v\/|shorts\/))([a-zA-Z0-9_-]{11})`)
+
 func (y *YouTubeScraper) extractYouTubeID(url string) (string, error) {
-	re := regexp.MustCompile(`(?:youtu\.be\/|youtube\.com\/(?:watch\?v=|embed\/|v\/|shorts\/))([a-zA-Z0-9_-]{11})`)
-	matches := re.FindStringSubmatch(url)
+	return ExtractYouTubeID(url)
+}
+
+// ExtractYouTubeID returns the 11 character video ID of a watch, embed, shorts or youtu.be URL.
+func ExtractYouTubeID(url string) (string, error) {
+	matches := youtubeIDRe.FindStringSubmatch(url)
 	if len(matches) < 2 {
 		return "", fmt.Errorf("failed to extract video ID from URL")
 	}
 	return matches[1], nil
 }
 
-func (y *YouTubeScraper) Download(link, format string) (string, error) {
-	apiBase := "https://media.savetube.me/api"
-	apiCDN := "/random-cdn"
-	apiInfo := "/v2/info"
-	apiDownload := "/download"
+const savetubeAPIBase = "https://media.savetube.me/api"
+
+var (
+	defaultVideoQualities = []string{"1080", "720", "480", "360", "240", "144"}
+	defaultAudioQualities = []string{"128"}
+
+	// approximate total bitrates in kbps, used to estimate file sizes
+	videoBitrates = map[int]int{
+		144:  150,
+		240:  300,
+		360:  600,
+		480:  1100,
+		720:  2500,
+		1080: 4500,
+		1440: 9000,
+		2160: 18000,
+	}
+)
+
+type Format struct {
+	Quality       string `json:"quality"`
+	Label         string `json:"label"`
+	EstimatedSize int64  `json:"estimatedSize"`
+}
+
+type FormatList struct {
+	Title     string   `json:"title"`
+	Duration  float64  `json:"duration"`
+	Thumbnail string   `json:"thumbnail"`
+	Video     []Format `json:"video"`
+	Audio     []Format `json:"audio"`
+}
+
+type savetubeInfo struct {
+	Key          string  `json:"key"`
+	Title        string  `json:"title"`
+	Duration     float64 `json:"duration"`
+	Thumbnail    string  `json:"thumbnail"`
+	VideoFormats []struct {
+		Quality int    `json:"quality"`
+		Label   string `json:"label"`
+	} `json:"video_formats"`
+	AudioFormats []struct {
+		Quality int    `json:"quality"`
+		Label   string `json:"label"`
+	} `json:"audio_formats"`
+}
+
+type savetubeSession struct {
+	cdn     string
+	videoID string
+	info    savetubeInfo
+}
 
+func (y *YouTubeScraper) newSession(link string) (*savetubeSession, error) {
 	videoID, err := y.extractYouTubeID(link)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	cdnRes, err := y.makeRequest("GET", apiBase+apiCDN, map[string]string{})
+	cdnRes, err := y.makeRequest("GET", savetubeAPIBase+"/random-cdn", map[string]string{})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	cdn, ok := cdnRes["cdn"].(string)
+	if !ok {
+		return nil, fmt.Errorf("no CDN in response")
 	}
-	cdn := cdnRes["cdn"].(string)
 
-	infoRes, err := y.makeRequest("POST", "https://"+cdn+apiInfo, map[string]string{
+	infoRes, err := y.makeRequest("POST", "https://"+cdn+"/v2/info", map[string]string{
 		"url": "https://www.youtube.com/watch?v=" + videoID,
 	})
+	if err != nil {
+		return nil, err
+	}
+	encrypted, ok := infoRes["data"].(string)
+	if !ok {
+		return nil, fmt.Errorf("no info data in response")
+	}
+
+	decrypted, err := y.decryptData(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	// decryptData yields a generic map; round-trip it into the typed struct
+	raw, _ := json.Marshal(decrypted)
+	var info savetubeInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse video info: %v", err)
+	}
+	if info.Key == "" {
+		return nil, fmt.Errorf("no download key in video info")
+	}
+
+	return &savetubeSession{cdn: cdn, videoID: videoID, info: info}, nil
+}
+
+func (s *savetubeSession) videoQualities() []string {
+	if len(s.info.VideoFormats) == 0 {
+		return defaultVideoQualities
+	}
+	qualities := make([]int, 0, len(s.info.VideoFormats))
+	for _, f := range s.info.VideoFormats {
+		qualities = append(qualities, f.Quality)
+	}
+	return sortQualities(qualities)
+}
+
+func (s *savetubeSession) audioQualities() []string {
+	if len(s.info.AudioFormats) == 0 {
+		return defaultAudioQualities
+	}
+	qualities := make([]int, 0, len(s.info.AudioFormats))
+	for _, f := range s.info.AudioFormats {
+		qualities = append(qualities, f.Quality)
+	}
+	return sortQualities(qualities)
+}
+
+// sortQualities returns the distinct qualities as strings, highest first.
+func sortQualities(qualities []int) []string {
+	sort.Sort(sort.Reverse(sort.IntSlice(qualities)))
+	result := make([]string, 0, len(qualities))
+	for i, q := range qualities {
+		if i > 0 && q == qualities[i-1] {
+			continue
+		}
+		result = append(result, strconv.Itoa(q))
+	}
+	return result
+}
+
+// qualityValue parses a quality such as "720", "720p" or "128kbps" to its
+// number, or 0 when it has none.
+func qualityValue(quality string) int {
+	digits := strings.TrimRightFunc(strings.TrimSpace(quality), func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// downgraded reports whether selected is below the requested quality. A
+// fallback to a higher quality, or an unparseable request, is not a downgrade.
+func downgraded(requested, selected string) bool {
+	want := qualityValue(requested)
+	return want > 0 && qualityValue(selected) < want
+}
+
+func estimateSize(downloadType, quality string, duration float64) int64 {
+	q, err := strconv.Atoi(quality)
+	if err != nil {
+		return 0
+	}
+
+	kbps := q
+	if downloadType == "video" {
+		kbps = videoBitrates[q]
+		if kbps == 0 {
+			kbps = q * 4
+		}
+	}
+	return int64(float64(kbps) * 1000 / 8 * duration)
+}
+
+func (y *YouTubeScraper) downloadURL(s *savetubeSession, downloadType, quality string) (string, error) {
+	downloadRes, err := y.makeRequest("POST", "https://"+s.cdn+"/download", map[string]string{
+		"id":           s.videoID,
+		"downloadType": downloadType,
+		"quality":      quality,
+		"key":          s.info.Key,
+	})
 	if err != nil {
 		return "", err
 	}
 
-	decrypted, err := y.decryptData(infoRes["data"].(string))
+	data, ok := downloadRes["data"].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("no download data in response")
+	}
+	downloadURL, ok := data["downloadUrl"].(string)
+	if !ok || downloadURL == "" {
+		return "", fmt.Errorf("no download URL in response")
+	}
+	return downloadURL, nil
+}
+
+// contentLength asks the CDN for the file size, returning 0 when it is unknown.
+func (y *YouTubeScraper) contentLength(url string) int64 {
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return 0
+	}
+
+	resp, err := y.client.Do(req)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0
+	}
+	return resp.ContentLength
+}
+
+func (y *YouTubeScraper) Download(link, format string) (string, error) {
+	session, err := y.newSession(link)
 	if err != nil {
 		return "", err
 	}
@@ -211,26 +417,110 @@ func (y *YouTubeScraper) Download(link, format string) (string, error) {
 		quality = "128"
 	}
 
-	downloadRes, err := y.makeRequest("POST", "https://"+cdn+apiDownload, map[string]string{
-		"id":           videoID,
-		"downloadType": downloadType,
-		"quality":      quality,
-		"key":          decrypted["key"].(string),
-	})
+	return y.downloadURL(session, downloadType, quality)
+}
+
+// Formats lists the video qualities and audio bitrates available for url,
+// highest first, with sizes estimated from the video duration.
+func (y *YouTubeScraper) Formats(url string) (*FormatList, error) {
+	session, err := y.newSession(url)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	list := &FormatList{
+		Title:     session.info.Title,
+		Duration:  session.info.Duration,
+		Thumbnail: session.info.Thumbnail,
+	}
+	for _, q := range session.videoQualities() {
+		list.Video = append(list.Video, Format{
+			Quality:       q,
+			Label:         q + "p",
+			EstimatedSize: estimateSize("video", q, session.info.Duration),
+		})
+	}
+	for _, q := range session.audioQualities() {
+		list.Audio = append(list.Audio, Format{
+			Quality:       q,
+			Label:         q + "kbps",
+			EstimatedSize: estimateSize("audio", q, session.info.Duration),
+		})
+	}
+
+	return list, nil
+}
+
+// downloadWithLimit tries the requested quality and then every lower one until
+// the file fits within maxBytes. A maxBytes of 0 disables the limit.
+func (y *YouTubeScraper) downloadWithLimit(s *savetubeSession, downloadType, quality string, maxBytes int64) (string, string, int64, error) {
+	available := s.videoQualities()
+	if downloadType == "audio" {
+		available = s.audioQualities()
+	}
+
+	requested := qualityValue(quality)
+
+	var candidates []string
+	for _, q := range available {
+		if n, _ := strconv.Atoi(q); requested == 0 || n <= requested {
+			candidates = append(candidates, q)
+		}
+	}
+	if len(candidates) == 0 {
+		// nothing at or below the requested quality, fall back to the lowest one
+		candidates = available[len(available)-1:]
+	}
+
+	var lastErr error
+	for _, q := range candidates {
+		if maxBytes > 0 && estimateSize(downloadType, q, s.info.Duration) > maxBytes*2 {
+			// far too large even allowing for a bad estimate, don't bother the CDN
+			continue
+		}
+
+		downloadURL, err := y.downloadURL(s, downloadType, q)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		size := y.contentLength(downloadURL)
+		if size == 0 {
+			size = estimateSize(downloadType, q, s.info.Duration)
+		}
+		if maxBytes > 0 && size > maxBytes {
+			lastErr = fmt.Errorf("%s %s is %d bytes, over the %d byte limit", downloadType, q, size, maxBytes)
+			continue
+		}
+
+		return downloadURL, q, size, nil
 	}
 
-	return downloadRes["data"].(map[string]interface{})["downloadUrl"].(string), nil
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no %s format fits within %d bytes", downloadType, maxBytes)
+	}
+	return "", "", 0, lastErr
 }
 
-func (y *YouTubeScraper) Audio(url string) (*DownloadResult, error) {
+// Audio downloads audio at the requested bitrate, or the next lower one that
+// fits within maxBytes. An empty bitrate means DefaultAudioQuality.
+func (y *YouTubeScraper) Audio(url, bitrate string, maxBytes int64) (*DownloadResult, error) {
+	if bitrate == "" {
+		bitrate = DefaultAudioQuality
+	}
+
 	info, err := y.Info(url)
 	if err != nil {
 		return nil, err
 	}
 
-	downloadURL, err := y.Download(url, "mp3")
+	session, err := y.newSession(url)
+	if err != nil {
+		return nil, err
+	}
+
+	downloadURL, quality, size, err := y.downloadWithLimit(session, "audio", bitrate, maxBytes)
 	if err != nil {
 		return &DownloadResult{
 			Status:  false,
@@ -239,21 +529,35 @@ func (y *YouTubeScraper) Audio(url string) (*DownloadResult, error) {
 	}
 
 	return &DownloadResult{
-		Status:    true,
-		Title:     info.Title,
-		Time:      info.Duration / 60,
-		URL:       downloadURL,
-		Thumbnail: info.Thumbnail,
+		Status:     true,
+		Title:      info.Title,
+		Time:       info.Duration / 60,
+		URL:        downloadURL,
+		Thumbnail:  info.Thumbnail,
+		Quality:    quality,
+		Size:       size,
+		Downgraded: downgraded(bitrate, quality),
 	}, nil
 }
 
-func (y *YouTubeScraper) Video(url, quality string) (*DownloadResult, error) {
+// Video downloads video at the requested quality, or the next lower one that
+// fits within maxBytes. An empty quality means DefaultVideoQuality.
+func (y *YouTubeScraper) Video(url, quality string, maxBytes int64) (*DownloadResult, error) {
+	if quality == "" {
+		quality = DefaultVideoQuality
+	}
+
 	info, err := y.Info(url)
 	if err != nil {
 		return nil, err
 	}
 
-	downloadURL, err := y.Download(url, quality)
+	session, err := y.newSession(url)
+	if err != nil {
+		return nil, err
+	}
+
+	downloadURL, selected, size, err := y.downloadWithLimit(session, "video", quality, maxBytes)
 	if err != nil {
 		return &DownloadResult{
 			Status:  false,
@@ -262,9 +566,12 @@ func (y *YouTubeScraper) Video(url, quality string) (*DownloadResult, error) {
 	}
 
 	return &DownloadResult{
-		Status: true,
-		Title:  info.Title,
-		Time:   info.Duration / 60,
-		URL:    downloadURL,
+		Status:     true,
+		Title:      info.Title,
+		Time:       info.Duration / 60,
+		URL:        downloadURL,
+		Quality:    selected,
+		Size:       size,
+		Downgraded: downgraded(quality, selected),
 	}, nil
 }
@@ -0,0 +1,164 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+const youtubePlaylistURL = "https://www.youtube.com/playlist?list="
+
+var playlistIDRe = regexp.MustCompile(`[?&]list=([a-zA-Z0-9_-]+)`)
+
+type Playlist struct {
+	ID      string          `json:"id"`
+	Title   string          `json:"title"`
+	Entries []PlaylistEntry `json:"entries"`
+}
+
+type PlaylistEntry struct {
+	Index     int    `json:"index"`
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Channel   string `json:"channel"`
+	Duration  int    `json:"duration"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+type ytPlaylistData struct {
+	Metadata struct {
+		PlaylistMetadataRenderer struct {
+			Title string `json:"title"`
+		} `json:"playlistMetadataRenderer"`
+	} `json:"metadata"`
+	Contents struct {
+		TwoColumnBrowseResultsRenderer struct {
+			Tabs []struct {
+				TabRenderer struct {
+					Content struct {
+						SectionListRenderer struct {
+							Contents []struct {
+								ItemSectionRenderer struct {
+									Contents []struct {
+										PlaylistVideoListRenderer struct {
+											Contents []struct {
+												PlaylistVideoRenderer *struct {
+													VideoID         string `json:"videoId"`
+													Title           ytText `json:"title"`
+													ShortBylineText ytText `json:"shortBylineText"`
+													LengthSeconds   string `json:"lengthSeconds"`
+													IsPlayable      bool   `json:"isPlayable"`
+													Thumbnail       struct {
+														Thumbnails []struct {
+															URL string `json:"url"`
+														} `json:"thumbnails"`
+													} `json:"thumbnail"`
+												} `json:"playlistVideoRenderer"`
+											} `json:"contents"`
+										} `json:"playlistVideoListRenderer"`
+									} `json:"contents"`
+								} `json:"itemSectionRenderer"`
+							} `json:"contents"`
+						} `json:"sectionListRenderer"`
+					} `json:"content"`
+				} `json:"tabRenderer"`
+			} `json:"tabs"`
+		} `json:"twoColumnBrowseResultsRenderer"`
+	} `json:"contents"`
+}
+
+// ExtractPlaylistID returns the list= parameter of a YouTube URL, or an empty
+// string when the URL doesn't reference a playlist.
+func ExtractPlaylistID(url string) string {
+	matches := playlistIDRe.FindStringSubmatch(url)
+	if len(matches) < 2 {
+		return ""
+	}
+	return matches[1]
+}
+
+// Playlist enumerates the entries of a public playlist. Only the first page
+// (up to 100 videos) is read; unavailable videos are skipped.
+func (y *YouTubeScraper) Playlist(url string) (*Playlist, error) {
+	listID := ExtractPlaylistID(url)
+	if listID == "" {
+		return nil, errors.New("failed to extract playlist ID from URL")
+	}
+
+	req, err := http.NewRequest("GET", youtubePlaylistURL+listID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("request creation failed: %v", err)
+	}
+
+	req.Header.Set("accept-language", "en-US,en;q=0.9")
+	req.Header.Set("user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+
+	resp, err := y.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("playlist request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("playlist returned status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	matches := ytInitialDataRe.FindSubmatch(body)
+	if len(matches) < 2 {
+		return nil, errors.New("playlist data not found in page")
+	}
+
+	var data ytPlaylistData
+	if err := json.Unmarshal(matches[1], &data); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist: %v", err)
+	}
+
+	playlist := &Playlist{
+		ID:    listID,
+		Title: data.Metadata.PlaylistMetadataRenderer.Title,
+	}
+
+	for _, tab := range data.Contents.TwoColumnBrowseResultsRenderer.Tabs {
+		for _, section := range tab.TabRenderer.Content.SectionListRenderer.Contents {
+			for _, item := range section.ItemSectionRenderer.Contents {
+				for _, entry := range item.PlaylistVideoListRenderer.Contents {
+					video := entry.PlaylistVideoRenderer
+					if video == nil || video.VideoID == "" || !video.IsPlayable {
+						continue
+					}
+
+					duration, _ := strconv.Atoi(video.LengthSeconds)
+					thumbnail := ""
+					if thumbs := video.Thumbnail.Thumbnails; len(thumbs) > 0 {
+						thumbnail = thumbs[len(thumbs)-1].URL
+					}
+
+					playlist.Entries = append(playlist.Entries, PlaylistEntry{
+						Index:     len(playlist.Entries) + 1,
+						ID:        video.VideoID,
+						URL:       "https://www.youtube.com/watch?v=" + video.VideoID,
+						Title:     video.Title.String(),
+						Channel:   video.ShortBylineText.String(),
+						Duration:  duration,
+						Thumbnail: thumbnail,
+					})
+				}
+			}
+		}
+	}
+
+	if len(playlist.Entries) == 0 {
+		return nil, errors.New("playlist is empty or private")
+	}
+	return playlist, nil
+}
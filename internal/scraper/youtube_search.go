@@ -0,0 +1,184 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const youtubeSearchURL = "https://www.youtube.com/results"
+
+var ytInitialDataRe = regexp.MustCompile(`(?s)var ytInitialData\s*=\s*(\{.*?\});\s*</script>`)
+
+type SearchResult struct {
+	Rank      int    `json:"rank"`
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Channel   string `json:"channel"`
+	Duration  int    `json:"duration"`
+	Views     int64  `json:"views"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+type ytText struct {
+	SimpleText string `json:"simpleText"`
+	Runs       []struct {
+		Text string `json:"text"`
+	} `json:"runs"`
+}
+
+func (t ytText) String() string {
+	if t.SimpleText != "" {
+		return t.SimpleText
+	}
+	var sb strings.Builder
+	for _, run := range t.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type ytVideoRenderer struct {
+	VideoID       string `json:"videoId"`
+	Title         ytText `json:"title"`
+	OwnerText     ytText `json:"ownerText"`
+	LengthText    ytText `json:"lengthText"`
+	ViewCountText ytText `json:"viewCountText"`
+	Thumbnail     struct {
+		Thumbnails []struct {
+			URL string `json:"url"`
+		} `json:"thumbnails"`
+	} `json:"thumbnail"`
+}
+
+type ytSearchData struct {
+	Contents struct {
+		TwoColumnSearchResultsRenderer struct {
+			PrimaryContents struct {
+				SectionListRenderer struct {
+					Contents []struct {
+						ItemSectionRenderer struct {
+							Contents []struct {
+								VideoRenderer *ytVideoRenderer `json:"videoRenderer"`
+							} `json:"contents"`
+						} `json:"itemSectionRenderer"`
+					} `json:"contents"`
+				} `json:"sectionListRenderer"`
+			} `json:"primaryContents"`
+		} `json:"twoColumnSearchResultsRenderer"`
+	} `json:"contents"`
+}
+
+// Search looks up videos matching query and returns up to limit results in
+// YouTube's relevance order. Live streams, which have no duration, are skipped.
+func (y *YouTubeScraper) Search(query string, limit int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("query cannot be empty")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	req, err := http.NewRequest("GET", youtubeSearchURL+"?"+url.Values{
+		"search_query": {query},
+		"sp":           {"EgIQAQ=="}, // videos only
+	}.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("request creation failed: %v", err)
+	}
+
+	req.Header.Set("accept-language", "en-US,en;q=0.9")
+	req.Header.Set("user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+
+	resp, err := y.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("search request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search returned status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	matches := ytInitialDataRe.FindSubmatch(body)
+	if len(matches) < 2 {
+		return nil, errors.New("search results not found in page")
+	}
+
+	var data ytSearchData
+	if err := json.Unmarshal(matches[1], &data); err != nil {
+		return nil, fmt.Errorf("failed to parse search results: %v", err)
+	}
+
+	var results []SearchResult
+	for _, section := range data.Contents.TwoColumnSearchResultsRenderer.PrimaryContents.SectionListRenderer.Contents {
+		for _, item := range section.ItemSectionRenderer.Contents {
+			video := item.VideoRenderer
+			if video == nil || video.VideoID == "" || video.LengthText.String() == "" {
+				continue
+			}
+
+			thumbnail := ""
+			if thumbs := video.Thumbnail.Thumbnails; len(thumbs) > 0 {
+				thumbnail = thumbs[len(thumbs)-1].URL
+			}
+
+			results = append(results, SearchResult{
+				Rank:      len(results) + 1,
+				ID:        video.VideoID,
+				URL:       "https://www.youtube.com/watch?v=" + video.VideoID,
+				Title:     video.Title.String(),
+				Channel:   video.OwnerText.String(),
+				Duration:  parseClockDuration(video.LengthText.String()),
+				Views:     parseViewCount(video.ViewCountText.String()),
+				Thumbnail: thumbnail,
+			})
+			if len(results) == limit {
+				return results, nil
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no results for %q", query)
+	}
+	return results, nil
+}
+
+// parseClockDuration converts "1:02:03" or "3:45" into seconds.
+func parseClockDuration(s string) int {
+	total := 0
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return total
+}
+
+// parseViewCount extracts the digits from strings like "1,234,567 views".
+func parseViewCount(s string) int64 {
+	var digits strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	n, _ := strconv.ParseInt(digits.String(), 10, 64)
+	return n
+}
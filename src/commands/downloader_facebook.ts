@@ -0,0 +1,41 @@
+import { Command } from '../types'
+
+export default {
+  name: 'facebook',
+  alias: ['fb', 'fbdl'],
+  category: 'downloader',
+  wait: true,
+  description: 'Download Facebook videos and reels',
+  async handler(bot, args, context) {
+    if (!args.length) {
+      return bot.sendMessage(context.chat,
+        '⚠️ Please provide a Facebook URL\nExample: /fb https://fb.watch/xyz'
+      )
+    }
+
+    const url = args[0]
+    if (!url.match(/facebook\.com|fb\.watch|fb\.com/)) {
+      return bot.sendMessage(context.chat,
+        '❌ Invalid Facebook URL. Please provide a valid video link.'
+      )
+    }
+
+    try {
+      const { result, error } = await bot.downloader(url, 'facebook')
+      if (!result?.video) {
+        throw new Error(error || 'No video found in response')
+      }
+
+      const quality = result.variants?.[0]?.quality?.toUpperCase()
+      await bot.sendVideo(context.chat, result.video,
+        [result.title, quality && `🎞 ${quality}`].filter(Boolean).join('\n'),
+        true
+      )
+    } catch (error) {
+      const errorMessage = error instanceof Error
+        ? error.message
+        : 'An unknown error occurred'
+      await bot.sendMessage(context.chat, `❌ Failed to download Facebook video: ${errorMessage}`)
+    }
+  }
+} as Command
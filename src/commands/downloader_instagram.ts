@@ -0,0 +1,48 @@
+import { Command } from '../types'
+
+export default {
+  name: 'instagram',
+  alias: ['ig', 'igdl'],
+  category: 'downloader',
+  wait: true,
+  description: 'Download Instagram reels, posts and carousels',
+  async handler(bot, args, context) {
+    if (!args.length) {
+      return bot.sendMessage(context.chat,
+        '⚠️ Please provide an Instagram URL\nExample: /ig https://www.instagram.com/reel/xyz'
+      )
+    }
+
+    const url = args[0]
+    if (!url.match(/instagram\.com\/(?:[\w.]+\/)?(p|reels?|tv|stories)\//)) {
+      return bot.sendMessage(context.chat,
+        '❌ Invalid Instagram URL. Please provide a valid post or reel link.'
+      )
+    }
+
+    try {
+      const { result, error } = await bot.downloader(url, 'instagram')
+      if (!result?.items?.length) {
+        throw new Error(error || 'No media found in response')
+      }
+
+      const caption = [
+        result.author && `👤 @${result.author}`,
+        result.title
+      ].filter(Boolean).join('\n')
+
+      if (result.items.length > 1) {
+        await bot.sendAlbum(context.chat, result.items.map(item => item.url), caption)
+      } else if (result.items[0].type === 'video') {
+        await bot.sendVideo(context.chat, result.items[0].url, caption, true)
+      } else {
+        await bot.sendImage(context.chat, result.items[0].url, caption, true)
+      }
+    } catch (error) {
+      const errorMessage = error instanceof Error
+        ? error.message
+        : 'An unknown error occurred'
+      await bot.sendMessage(context.chat, `❌ Failed to download Instagram content: ${errorMessage}`)
+    }
+  }
+} as Command
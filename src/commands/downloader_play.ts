@@ -0,0 +1,46 @@
+import { Command } from '../types'
+
+export default {
+  name: 'play',
+  alias: ['song', 'yts'],
+  category: 'downloader',
+  description: 'Search YouTube and send the top result as audio',
+  wait: true,
+  async handler(bot, args, context) {
+    if (!args.length) {
+      return bot.sendMessage(context.chat,
+        '⚠️ Please provide a song title\nExample: /play never gonna give you up'
+      )
+    }
+
+    const query = args.join(' ')
+
+    try {
+      const search = await bot.search(query, 'youtube', 1)
+      const video = search.result?.[0]
+      if (!video) {
+        throw new Error(search.error || 'No results found')
+      }
+
+      const { result, error } = await bot.downloader(video.url, 'youtube', 'mp3')
+      if (!result?.url) {
+        throw new Error(error || 'No audio URL received')
+      }
+
+      const minutes = Math.floor(video.duration / 60)
+      const seconds = `${video.duration % 60}`.padStart(2, '0')
+
+      await bot.sendImage(context.chat, video.thumbnail,
+        `🎵 *${video.title}*\n` +
+        `👤 ${video.channel}\n` +
+        `⏱ ${minutes}:${seconds}  👁 ${video.views.toLocaleString()} views\n` +
+        `🔗 ${video.url}`,
+        true
+      )
+      await bot.sendAudio(context.chat, result.url, true)
+    } catch (error: any) {
+      console.error('[Play] Error:', error)
+      await bot.sendMessage(context.chat, `❌ Failed to play "${query}": ${error.message}`)
+    }
+  }
+} as Command
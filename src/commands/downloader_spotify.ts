@@ -0,0 +1,51 @@
+import { Command } from '../types'
+
+export default {
+  name: 'spotify',
+  alias: ['sp', 'spdl'],
+  category: 'downloader',
+  wait: true,
+  description: 'Download a Spotify track as MP3',
+  async handler(bot, args, context) {
+    if (!args.length) {
+      return bot.sendMessage(context.chat,
+        '⚠️ Please provide a Spotify track URL\nExample: /spotify https://open.spotify.com/track/xyz'
+      )
+    }
+
+    const url = args[0]
+    if (!url.match(/open\.spotify\.com\/(intl-[a-z]+\/)?track\/[A-Za-z0-9]{22}/)) {
+      return bot.sendMessage(context.chat,
+        '❌ Invalid Spotify URL. Please provide a valid track link.'
+      )
+    }
+
+    try {
+      const { result, error } = await bot.downloader(url, 'spotify')
+      if (!result?.url) {
+        throw new Error(error || 'No audio URL received')
+      }
+
+      const duration = result.duration
+        ? `${Math.floor(result.duration / 60)}:${`${result.duration % 60}`.padStart(2, '0')}`
+        : 'Unknown'
+      const caption =
+        `🎵 *${result.title}*\n` +
+        `👤 ${(result.artists || []).join(', ') || 'Unknown'}\n` +
+        (result.album ? `💿 ${result.album}\n` : '') +
+        `⏱ ${duration}`
+
+      if (result.cover) {
+        await bot.sendImage(context.chat, result.cover, caption, true)
+      } else {
+        await bot.sendMessage(context.chat, caption)
+      }
+      await bot.sendAudio(context.chat, result.url, true)
+    } catch (error) {
+      const errorMessage = error instanceof Error
+        ? error.message
+        : 'An unknown error occurred'
+      await bot.sendMessage(context.chat, `❌ Failed to download Spotify track: ${errorMessage}`)
+    }
+  }
+} as Command
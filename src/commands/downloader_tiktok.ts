@@ -1,4 +1,15 @@
-import { Command } from '../types'
+import { Command, DownloadResult } from '../types'
+
+const MAX_VIDEO_SIZE = 64 * 1024 * 1024
+
+const formatCount = (n = 0) =>
+  n >= 1e6 ? `${(n / 1e6).toFixed(1)}M` : n >= 1e3 ? `${(n / 1e3).toFixed(1)}K` : `${n}`
+
+const buildCaption = (result: NonNullable<DownloadResult['result']>) => [
+  result.title,
+  result.author && `👤 @${result.author}${result.authorName ? ` (${result.authorName})` : ''}`,
+  `▶️ ${formatCount(result.playCount)}  ❤️ ${formatCount(result.likeCount)}  💬 ${formatCount(result.commentCount)}  🔁 ${formatCount(result.shareCount)}`
+].filter(Boolean).join('\n')
 
 export default {
   name: 'tiktok',
@@ -42,17 +53,17 @@ export default {
 
         const targetChat = sendPrivate ? sender : context.chat
         const musicTarget = sendPrivate ? sender : context.chat
-
-        for (const image of result.images) {
-          sendOperations.push(bot.sendImage(targetChat, image))
-        }
+        sendOperations.push(bot.sendAlbum(targetChat, result.images, buildCaption(result)))
 
         if (result.music) {
           sendOperations.push(bot.sendAudio(musicTarget, result.music, !sendPrivate))
         }
       } else if (result.video) {
+        const videoUrl = result.hd && result.hdSize && result.hdSize <= MAX_VIDEO_SIZE
+          ? result.hd
+          : result.video
         sendOperations.push(
-          bot.sendVideo(context.chat, result.video, 'TikTok Video', true)
+          bot.sendVideo(context.chat, videoUrl, buildCaption(result), true)
         )
         
         if (result.music) {
@@ -0,0 +1,51 @@
+import { Command } from '../types'
+
+export default {
+  name: 'twitter',
+  alias: ['x', 'twdl', 'xdl'],
+  category: 'downloader',
+  wait: true,
+  description: 'Download videos, GIFs and photos from Twitter/X',
+  async handler(bot, args, context) {
+    if (!args.length) {
+      return bot.sendMessage(context.chat,
+        '⚠️ Please provide a Twitter/X URL\nExample: /x https://x.com/user/status/123'
+      )
+    }
+
+    const url = args[0]
+    if (!url.match(/(twitter|x)\.com\/[\w/]+\/status(es)?\/\d+/)) {
+      return bot.sendMessage(context.chat,
+        '❌ Invalid Twitter/X URL. Please provide a valid post link.'
+      )
+    }
+
+    try {
+      const { result, error } = await bot.downloader(url, 'twitter')
+      if (!result?.items?.length) {
+        throw new Error(error || 'No media found in response')
+      }
+
+      const caption = [
+        result.author && `👤 @${result.author}`,
+        result.title
+      ].filter(Boolean).join('\n')
+
+      for (const [index, item] of result.items.entries()) {
+        const itemCaption = index === 0 ? caption : ''
+        if (item.type === 'gif') {
+          await bot.sendGif(context.chat, item.url, itemCaption, true)
+        } else if (item.type === 'video') {
+          await bot.sendVideo(context.chat, item.url, itemCaption, true)
+        } else {
+          await bot.sendImage(context.chat, item.url, itemCaption, true)
+        }
+      }
+    } catch (error) {
+      const errorMessage = error instanceof Error
+        ? error.message
+        : 'An unknown error occurred'
+      await bot.sendMessage(context.chat, `❌ Failed to download Twitter/X content: ${errorMessage}`)
+    }
+  }
+} as Command
@@ -9,7 +9,7 @@ export default {
   async handler(bot, args, context) {
     if (!args.length) {
       return bot.sendMessage(context.chat,
-        '⚠️ Please provide a YouTube URL\nExample: /ytmp3 https://youtu.be/dQw4w9WgXcQ'
+        '⚠️ Please provide a YouTube URL\nExample: /ytmp3 https://youtu.be/dQw4w9WgXcQ 128'
       )
     }
 
@@ -22,7 +22,8 @@ export default {
     }
 
     try {
-      const result = await bot.downloader(url, 'youtube', 'mp3')
+      const bitrate = args[1]?.replace(/kbps$/i, '')
+      const result = await bot.downloader(url, 'youtube', 'mp3', bitrate)
       console.log('YouTube MP3 Result:', result)
 
       if (!result?.result?.url) {
@@ -9,7 +9,7 @@ export default {
     if (!args.length) {
       return bot.sendMessage(
         context.chat,
-        '⚠️ Please provide a YouTube URL\nExample: /ytmp4 https://youtu.be/dQw4w9WgXcQ'
+        '⚠️ Please provide a YouTube URL\nExample: /ytmp4 https://youtu.be/dQw4w9WgXcQ 480'
       )
     }
 
@@ -26,7 +26,8 @@ export default {
     try {
       await bot.sendMessage(context.chat, '⏳ Downloading YouTube video... (This may take a while)')
 
-      const { result, error } = await bot.downloader(url, 'youtube', 'mp4')
+      const quality = args[1]?.replace(/p$/i, '')
+      const { result, error } = await bot.downloader(url, 'youtube', 'mp4', quality)
 
       if (!result?.url) {
         throw new Error(error || 'No video URL received')
@@ -45,7 +46,9 @@ export default {
         bot.sendMessage(
           context.chat,
           `✅ *${result.title || 'YouTube Video'}*\n` +
-          (result.duration ? `⏱ Duration: ${minutes}m ${seconds}s` : '')
+          (result.duration ? `⏱ Duration: ${minutes}m ${seconds}s` : '') +
+          (result.quality ? `\n🎞 Quality: ${result.quality}p` : '') +
+          (result.downgraded ? ' (lowered to fit WhatsApp size limit)' : '')
         )
       ])
 
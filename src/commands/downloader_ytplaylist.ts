@@ -0,0 +1,23 @@
+import { Command } from '../types'
+
+export default {
+  name: 'ytplaylist',
+  alias: ['ytpl', 'ytbatch'],
+  category: 'downloader',
+  description: 'Download a YouTube playlist or several videos/Shorts at once',
+  wait: true,
+  async handler(bot, args, context) {
+    const format = args[0] === 'mp3' || args[0] === 'mp4' ? args.shift() as 'mp3' | 'mp4' : 'mp4'
+    const urls = args.filter(arg => /youtu\.be\/|youtube\.com\//.test(arg))
+
+    if (!urls.length) {
+      return bot.sendMessage(context.chat,
+        '⚠️ Please provide a playlist URL or several YouTube links\n' +
+        'Example: /ytplaylist mp3 https://youtube.com/playlist?list=...'
+      )
+    }
+
+    await bot.sendMessage(context.chat, '⏳ Batch download started, files will arrive as they finish.')
+    await bot.batchDownload(context.chat, urls, format)
+  }
+} as Command
@@ -0,0 +1,42 @@
+import { Command } from '../types'
+
+const MAX_IMAGES = 10
+
+export default {
+  name: 'pinterest',
+  alias: ['pin', 'image', 'img'],
+  category: 'tools',
+  wait: true,
+  description: 'Search images on Pinterest',
+  async handler(bot, args, context) {
+    if (!args.length) {
+      return bot.sendMessage(context.chat,
+        '⚠️ Please provide a search query\nExample: /pin aesthetic cat 3'
+      )
+    }
+
+    const last = args[args.length - 1]
+    const count = /^\d+$/.test(last) && args.length > 1
+      ? Math.min(parseInt(args.pop()!, 10), MAX_IMAGES)
+      : 1
+    const query = args.join(' ')
+
+    try {
+      const { result, error } = await bot.search(query, 'image', count)
+      if (!result?.length) {
+        throw new Error(error || 'No images found')
+      }
+
+      if (result.length === 1) {
+        await bot.sendImage(context.chat, result[0].url, `🔎 ${query}`, true)
+      } else {
+        await bot.sendAlbum(context.chat, result.map(image => image.url), `🔎 ${query}`)
+      }
+    } catch (error) {
+      const errorMessage = error instanceof Error
+        ? error.message
+        : 'An unknown error occurred'
+      await bot.sendMessage(context.chat, `❌ Image search failed: ${errorMessage}`)
+    }
+  }
+} as Command
@@ -1,9 +1,24 @@
 import { ChildProcess } from 'child_process'
-import { AIResponse, Bot } from '../types'
+import { AIResponse, Bot, JobProgress, Requester } from '../types'
 
-export function createBotClient(botProcess: ChildProcess): Bot {
+let requestCounter = 0
+
+export function createBotClient(botProcess: ChildProcess, requester?: Requester): Bot {
   const formatContent = (content: string) => content.replace(/\n/g, '{{NL}}')
 
+  // Wraps a command in a REQ envelope so the Go side can schedule it fairly
+  // and tag its result with the request ID.
+  const requestIds = new Set<string>()
+  const withRequest = (command: string) => {
+    if (!requester) return { command, requestId: undefined }
+    const requestId = `${Date.now().toString(36)}-${(++requestCounter).toString(36)}`
+    requestIds.add(requestId)
+    return {
+      command: `REQ:${requestId}|${requester.chat}|${requester.sender}|${command}`,
+      requestId
+    }
+  }
+
   const sendCommand = (command: string, errorPrefix = 'Command') => {
     return new Promise<void>((resolve, reject) => {
       botProcess.stdin?.write(command, err => {
@@ -13,7 +28,7 @@ export function createBotClient(botProcess: ChildProcess): Bot {
   }
 
   const createMediaCommand = (
-    type: 'IMAGE' | 'VIDEO' | 'AUDIO',
+    type: 'IMAGE' | 'VIDEO' | 'GIF' | 'AUDIO',
     jid: string,
     media: string | Buffer,
     caption = '',
@@ -21,22 +36,41 @@ export function createBotClient(botProcess: ChildProcess): Bot {
   ) => {
     const baseCmd = isUrl || typeof media === 'string' ? `SEND_URL_${type}` : `SEND_${type}`
     const mediaData = typeof media === 'string' ? media : media.toString('base64')
-    return `${baseCmd}:${jid}|${mediaData}${type !== 'AUDIO' ? `|${formatContent(caption)}` : ''}MESSAGE_END\n`
+    const { command } = withRequest(`${baseCmd}:${jid}|${mediaData}${type !== 'AUDIO' ? `|${formatContent(caption)}` : ''}`)
+    return `${command}MESSAGE_END\n`
   }
 
-  const handleResponse = (prefix: string): Promise<any> => {
+  const onProgress = (handler: (progress: JobProgress) => void) => {
+    const listener = (data: Buffer) => {
+      for (const line of data.toString().split('\n')) {
+        if (!line.includes('"job_progress"')) continue
+        try {
+          const event = JSON.parse(line)
+          if (event.type === 'job_progress' && requestIds.has(event.content.requestId)) {
+            handler(event.content)
+          }
+        } catch {}
+      }
+    }
+    botProcess.stdout?.on('data', listener)
+    return () => { botProcess.stdout?.off('data', listener) }
+  }
+
+  const handleResponse = (prefix: string, requestId?: string): Promise<any> => {
     return new Promise((resolve, reject) => {
       const handler = (data: Buffer) => {
-        const message = data.toString()
-        if (message.includes(`${prefix}:`)) {
+        const chunks = data.toString().split(`${prefix}:`).slice(1)
+        for (const chunk of chunks) {
           try {
-            const jsonStr = message.split(`${prefix}:`)[1].split('MESSAGE_END')[0].trim()
-            resolve(JSON.parse(jsonStr))
+            const result = JSON.parse(chunk.split('MESSAGE_END')[0].trim())
+            if (requestId && result.requestId && result.requestId !== requestId) continue
+            botProcess.stdout?.off('data', handler)
+            resolve(result)
           } catch (err) {
-            reject(err)
-          } finally {
             botProcess.stdout?.off('data', handler)
+            reject(err)
           }
+          return
         }
       }
       botProcess.stdout?.on('data', handler)
@@ -88,6 +122,8 @@ export function createBotClient(botProcess: ChildProcess): Bot {
   return {
     ai,
     sendCommand,
+    forRequest: (next: Requester) => createBotClient(botProcess, next),
+    onProgress,
     sendMessage: (jid, content) => 
       sendCommand(`SEND:${jid}|${formatContent(typeof content === 'string' ? content : '')}MESSAGE_END\n`, 'Write'),
     
@@ -97,17 +133,125 @@ export function createBotClient(botProcess: ChildProcess): Bot {
     sendVideo: (jid, video, caption = '', isUrl = false) => 
       sendCommand(createMediaCommand('VIDEO', jid, video, caption, isUrl), 'Video send'),
     
+    sendGif: (jid, video, caption = '', isUrl = false) => 
+      sendCommand(createMediaCommand('GIF', jid, video, caption, isUrl), 'GIF send'),
+    
     sendAudio: (jid, audio, isUrl = false) => 
       sendCommand(createMediaCommand('AUDIO', jid, audio, '', isUrl), 'Audio send'),
     
-    downloader: async (url, type, format) => {
-      await sendCommand(`DOWNLOAD:${type}|${url}|${format || ''}MESSAGE_END\n`)
-      return handleResponse('DOWNLOAD_RESULT')
+    downloader: async (url, type, format, quality) => {
+      const { command, requestId } = withRequest(`DOWNLOAD:${type}|${url}|${format || ''}|${quality || ''}`)
+      await sendCommand(`${command}MESSAGE_END\n`)
+      return handleResponse('DOWNLOAD_RESULT', requestId)
+    },
+
+    search: async (query: string, type: 'youtube' | 'image', limit = 5) => {
+      const { command, requestId } = withRequest(`SEARCH:${type}|${query.replace(/\|/g, ' ')}|${limit}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Search')
+      return handleResponse('SEARCH_RESULT', requestId)
+    },
+
+    batchDownload: (jid, urls, format = 'mp4') => {
+      const { command } = withRequest(`BATCH_DOWNLOAD:${jid}|${format}|${urls.join('|')}`)
+      return sendCommand(`${command}MESSAGE_END\n`, 'Batch download')
+    },
+
+    formats: async (url, type) => {
+      const { command, requestId } = withRequest(`FORMATS:${type}|${url}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Formats')
+      return handleResponse('FORMATS_RESULT', requestId)
     },
     
     sendReaction: (jid, sender, messageId, emoji) => {
       const command = `REACT:${jid}|${messageId}|${formatContent(emoji)}|${sender}MESSAGE_END\n`
       return sendCommand(command, 'Reaction')
+    },
+
+    sendPoll: (jid, question, options, selectable = 1) => {
+      const command = `SEND_POLL:${jid}|${selectable}|${formatContent(question)}|${options.join('|')}MESSAGE_END\n`
+      return sendCommand(command, 'Poll send')
+    },
+
+    sendAlbum: (jid, urls, caption = '') => {
+      const { command } = withRequest(`SEND_ALBUM:${jid}|${formatContent(caption)}|${urls.join('|')}`)
+      return sendCommand(`${command}MESSAGE_END\n`, 'Album send')
+    },
+
+    sendLocation: (jid, latitude, longitude, name = '', address = '') => {
+      const command = `SEND_LOCATION:${jid}|${latitude}|${longitude}|${name}|${formatContent(address)}MESSAGE_END\n`
+      return sendCommand(command, 'Location send')
+    },
+
+    sendContact: (jid, contacts) => {
+      const pairs = contacts.map(c => `${c.name}|${c.phone}`).join('|')
+      return sendCommand(`SEND_CONTACT:${jid}|${pairs}MESSAGE_END\n`, 'Contact send')
+    },
+
+    nativeCommand: (text) => {
+      const { command } = withRequest(`COMMAND:${formatContent(text)}`)
+      return sendCommand(`${command}MESSAGE_END\n`, 'Native command')
+    },
+
+    broadcast: (targets, type, content, caption = '') => {
+      const { command } = withRequest(`BROADCAST:${targets.join(',')}|${type}|${formatContent(content)}|${formatContent(caption)}`)
+      return sendCommand(`${command}MESSAGE_END\n`, 'Broadcast')
+    },
+
+    schedule: async (chat, when, type, content, { timezone = '', caption = '' } = {}) => {
+      const { command, requestId } = withRequest(`SCHEDULE:add|${chat}|${when}|${timezone}|${type}|${formatContent(content)}|${formatContent(caption)}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Schedule')
+      return handleResponse('SCHEDULE_RESULT', requestId)
+    },
+
+    schedules: async (chat = '') => {
+      const { command, requestId } = withRequest(`SCHEDULE:list|${chat}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Schedule list')
+      return handleResponse('SCHEDULE_RESULT', requestId)
+    },
+
+    cancelSchedule: async (id) => {
+      const { command, requestId } = withRequest(`SCHEDULE:cancel|${id}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Schedule cancel')
+      return handleResponse('SCHEDULE_RESULT', requestId)
+    },
+
+    tag: async (action, tag, jids = []) => {
+      const { command, requestId } = withRequest(`TAG:${action}|${tag}|${jids.join(',')}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Tag')
+      return handleResponse('TAG_RESULT', requestId)
+    },
+
+    plugins: async () => {
+      const { command, requestId } = withRequest('PLUGINS:')
+      await sendCommand(`${command}MESSAGE_END\n`, 'Plugins')
+      return handleResponse('PLUGINS_RESULT', requestId)
+    },
+
+    jobs: async (chat = '', state = '', limit = 20) => {
+      const { command, requestId } = withRequest(`JOBS:${chat}|${state}|${limit}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Jobs')
+      return handleResponse('JOBS_RESULT', requestId)
+    },
+
+    roles: async (action, jid = '', role = '', chat = '') => {
+      const args = action === 'list' ? jid : `${jid}|${role}|${chat}`
+      const { command, requestId } = withRequest(`ROLE:${action}|${args}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Role')
+      return handleResponse('ROLE_RESULT', requestId)
+    },
+
+    hasRole: async (role, command = '') => {
+      const request = withRequest(`PERMISSION:${role}|${command}`)
+      if (!request.requestId) return true
+      await sendCommand(`${request.command}MESSAGE_END\n`, 'Permission')
+      const result = await handleResponse('PERMISSION_RESULT', request.requestId)
+      return result.allowed === true
+    },
+
+    pollResult: async (pollId) => {
+      const { command, requestId } = withRequest(`POLL_RESULT:${pollId}`)
+      await sendCommand(`${command}MESSAGE_END\n`, 'Poll result')
+      return handleResponse('POLL_RESULT', requestId)
     }
   }
 }
\ No newline at end of file
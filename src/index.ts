@@ -123,6 +123,58 @@ class WhatsAppBotLauncher {
           case 'chatbot_result':
             this.handleChatbotResponse(bot, message.content)
             break
+          case 'job_queued':
+            if (message.content.position > 1 && !['chatbot', 'scheduled'].includes(message.content.kind) && message.content.chat) {
+              bot.sendMessage(message.content.chat, `🕒 Your request is queued (position ${message.content.position})`)
+            }
+            break
+          case 'rate_limited':
+            console.warn(`[RATE] ${message.content.sender || message.content.chat} limited on ${message.content.kind} (${message.content.scope}), retry in ${message.content.retryAfter}s`)
+            // downloads and enhancements report the error through their own result
+            if (message.content.kind === 'chatbot' && message.content.chat) {
+              bot.sendMessage(message.content.chat, `🐢 Slow down a little, please try again in ${message.content.retryAfter} seconds.`)
+            }
+            break
+          case 'job_failed':
+            console.warn(`[JOBS] ${message.content.kind} request ${message.content.requestId} failed: ${message.content.error}`)
+            break
+          case 'job_rejected':
+            console.warn(`[JOBS] Rejected ${message.content.kind} request for ${message.content.chat}: ${message.content.error}`)
+            break
+          case 'batch_error':
+            bot.sendMessage(message.content.chat, `❌ Batch download failed: ${message.content.error}`)
+            break
+          case 'batch_done':
+            this.handleBatchDone(bot, message.content)
+            break
+          case 'broadcast_done':
+            this.handleBroadcastDone(bot, message.content)
+            break
+          case 'broadcast_error':
+            console.error(`[BROADCAST] ${message.content.requestId} failed: ${message.content.error}`)
+            if (message.content.chat) {
+              bot.sendMessage(message.content.chat, `❌ Broadcast failed: ${message.content.error}`)
+            }
+            break
+          case 'permission_denied':
+            console.warn(`[PERMISSION] ${message.content.sender} was denied ${message.content.kind} ${message.content.name} in ${message.content.chat} (needs ${message.content.role})`)
+            break
+          case 'plugin_denied':
+            console.warn(`[PLUGIN] ${message.content.plugin} was denied ${message.content.action} in ${message.content.chat} (needs ${message.content.permission})`)
+            break
+          case 'schedule_failed':
+            console.error(`[SCHEDULE] ${message.content.scheduleId || message.content.requestId} to ${message.content.chat} failed: ${message.content.error}`)
+            break
+          case 'schedule_skipped':
+            console.warn(`[SCHEDULE] ${message.content.scheduleId} skipped a run missed while offline`)
+            break
+          case 'media_error':
+            console.error(`[MEDIA] ${message.content.mediaType} to ${message.content.chat} failed: ${message.content.error}`)
+            if (message.content.blocked) {
+              console.warn(`[SECURITY] Blocked fetch of ${message.content.url} (${message.content.blocked.host} ${message.content.blocked.ip}): ${message.content.blocked.reason}`)
+            }
+            bot.sendMessage(message.content.chat, `⚠️ Failed to send ${message.content.mediaType}: ${message.content.error}`)
+            break
           default:
             if (output.includes('[BOT INFO]')) console.log(output)
         }
@@ -209,7 +261,9 @@ class WhatsAppBotLauncher {
             pushName: content.pushName || ''
           }
           
-          await cmd.handler(bot, query ? [query] : [], context)
+          const requestBot = bot.forRequest(context)
+          if (cmd.role && !(await requestBot.hasRole(cmd.role, command))) return
+          await cmd.handler(requestBot, query ? [query] : [], context)
         }
       } else if (isJsonResponse && parsedResponse.caption) {
         this.updateChatHistory(content.sender, 'assistant', parsedResponse.caption)
@@ -224,6 +278,32 @@ class WhatsAppBotLauncher {
     }
   }
 
+  private static async handleBatchDone(bot: Bot, content: any) {
+    const failed = (content.items || [])
+      .filter((item: any) => item.status !== 'done')
+      .map((item: any) => `• ${item.title || item.url}: ${item.error || item.status}`)
+
+    await bot.sendMessage(content.chat,
+      `📦 Batch finished: ${content.succeeded}/${content.total} sent` +
+      (content.skipped ? `, ${content.skipped} skipped` : '') +
+      (failed.length ? `\n\n${failed.join('\n')}` : '')
+    )
+  }
+
+  private static async handleBroadcastDone(bot: Bot, content: any) {
+    console.log(`[BROADCAST] ${content.requestId}: ${content.succeeded}/${content.total} sent`)
+    if (!content.chat) return
+
+    const failed = (content.results || [])
+      .filter((target: any) => target.status !== 'sent')
+      .map((target: any) => `• ${target.jid}: ${target.error}`)
+
+    await bot.sendMessage(content.chat,
+      `📢 Broadcast finished: ${content.succeeded}/${content.total} sent` +
+      (failed.length ? `\n\n${failed.join('\n')}` : '')
+    )
+  }
+
   private static updateChatHistory(sender: string, role: string, content: string) {
     if (!chatHistories[sender]) {
       chatHistories[sender] = { historyChatbot: [] }
@@ -250,7 +330,10 @@ class WhatsAppBotLauncher {
         messageId: content.quotedMessage.messageId || '',
         from: content.quotedMessage.from || '',
         isImage: content.quotedMessage.isImage || false
-      } : undefined
+      } : undefined,
+      mediaType: content.mediaType || undefined,
+      location: content.location,
+      contacts: content.contacts
     }
 
     if (!chatHistories[sender]) {
@@ -271,13 +354,18 @@ class WhatsAppBotLauncher {
       }
       if (!cmd) return
 
+      const requestBot = bot.forRequest(context)
+      if (cmd.role && !(await requestBot.hasRole(cmd.role, cmdName))) {
+        return await bot.sendMessage(chat, `🚫 ${cmdName} requires the ${cmd.role} role`)
+      }
+
       const startTime = Date.now()
       try {
         console.log(`[MSG] From: ${from} - Content: ${text}`)
         if (cmd.wait) {
           await bot.sendReaction(chat, sender, messageId, '⏳')
         }
-        await cmd.handler(bot, args, context)
+        await cmd.handler(requestBot, args, context)
         const duration = Date.now() - startTime
         if (duration > 1000) {
           console.log(`[PERF] Slow command ${cmdName}: ${duration}ms`)
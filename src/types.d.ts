@@ -1,5 +1,14 @@
+export interface Requester {
+  chat: string
+  sender: string
+}
+
 export interface Bot {
   sendCommand: (command: string, errorPrefix?: string) => Promise<void>
+  // Returns a client whose long-running requests are queued on behalf of requester
+  forRequest: (requester: Requester) => Bot
+  // Subscribes to job_progress events of requests made through this client
+  onProgress: (handler: (progress: JobProgress) => void) => () => void
   sendMessage: (jid: string, message: string) => Promise<void>
   sendImage: (
     jid: string, 
@@ -13,6 +22,12 @@ export interface Bot {
     caption?: string, 
     isUrl?: boolean
   ) => Promise<void>
+  sendGif: (
+    jid: string, 
+    video: Buffer | string, 
+    caption?: string, 
+    isUrl?: boolean
+  ) => Promise<void>
   sendAudio: (
     jid: string, 
     audio: Buffer | string, 
@@ -35,9 +50,198 @@ export interface Bot {
   ) => Promise<AIResponse>
   downloader: (
     url: string, 
-    type: 'tiktok' | 'youtube', 
-    format?: 'mp3' | 'mp4'
+    type: 'tiktok' | 'youtube' | 'instagram' | 'twitter' | 'facebook' | 'spotify', 
+    format?: 'mp3' | 'mp4',
+    quality?: string
   ) => Promise<DownloadResult>
+  batchDownload: (
+    jid: string,
+    urls: string[],
+    format?: 'mp3' | 'mp4'
+  ) => Promise<void>
+  formats: (
+    url: string,
+    type: 'youtube'
+  ) => Promise<FormatsResult>
+  search: {
+    (query: string, type: 'youtube', limit?: number): Promise<SearchResult<VideoSearchItem>>
+    (query: string, type: 'image', limit?: number): Promise<SearchResult<ImageSearchItem>>
+  }
+  sendPoll: (
+    jid: string,
+    question: string,
+    options: string[],
+    selectable?: number
+  ) => Promise<void>
+  pollResult: (pollId: string) => Promise<PollResult>
+  jobs: (chat?: string, state?: JobState | '', limit?: number) => Promise<JobsResult>
+  // runs a command built into the Go binary or one of its plugins for the current requester, e.g. 'stats'
+  nativeCommand: (text: string) => Promise<void>
+  plugins: () => Promise<PluginsResult>
+  // chat limits a grant to one chat; the owner role comes from OWNER_NUMBERS and cannot be granted
+  roles: (action: 'grant' | 'revoke' | 'list', jid?: string, role?: string, chat?: string) => Promise<RoleResult>
+  // asks Go whether the current requester holds role; always true without a requester
+  hasRole: (role: string, command?: string) => Promise<boolean>
+  // targets are JIDs, 'groups', 'contacts' or 'tag:<name>'; content is the text or a media URL
+  broadcast: (
+    targets: string[],
+    type: 'text' | 'image' | 'video' | 'gif' | 'audio',
+    content: string,
+    caption?: string
+  ) => Promise<void>
+  tag: (action: 'add' | 'remove' | 'list', tag: string, jids?: string[]) => Promise<TagResult>
+  // when is a local 'YYYY-MM-DD HH:mm' for a one-off message or a cron expression
+  schedule: (
+    chat: string,
+    when: string,
+    type: 'text' | 'image' | 'video' | 'gif' | 'audio',
+    content: string,
+    options?: { timezone?: string, caption?: string }
+  ) => Promise<ScheduleResult>
+  schedules: (chat?: string) => Promise<ScheduleResult>
+  cancelSchedule: (id: string) => Promise<ScheduleResult>
+  sendAlbum: (
+    jid: string,
+    urls: string[],
+    caption?: string
+  ) => Promise<void>
+  sendLocation: (
+    jid: string,
+    latitude: number,
+    longitude: number,
+    name?: string,
+    address?: string
+  ) => Promise<void>
+  sendContact: (
+    jid: string,
+    contacts: Array<{ name: string, phone: string }>
+  ) => Promise<void>
+}
+
+export interface JobProgress {
+  requestId: string
+  chat: string
+  stage: 'download' | 'upload'
+  bytes: number
+  total: number
+  percent: number
+  speed: number
+  eta: number
+  done: boolean
+}
+
+export interface TagResult {
+  status: boolean
+  tag: string
+  members?: string[]
+  error?: string
+}
+
+export interface Schedule {
+  id: string
+  chat: string
+  cron?: string
+  timezone: string
+  type: 'text' | 'image' | 'video' | 'gif' | 'audio'
+  content: string
+  caption?: string
+  nextRun: number
+  lastRun?: number
+  active: boolean
+  createdBy?: string
+  createdAt: number
+}
+
+export interface ScheduleResult {
+  status: boolean
+  schedule?: Schedule
+  schedules?: Schedule[]
+  error?: string
+}
+
+export type PluginPermission = 'reply' | 'react' | 'media' | 'send_any' | 'sender_info'
+
+export interface PluginsResult {
+  status: boolean
+  plugins?: Array<{
+    name: string
+    dir: string
+    commands: string[]
+    permissions: PluginPermission[]
+  }>
+  error?: string
+}
+
+export interface RoleGrant {
+  jid: string
+  chat?: string
+  role: string
+  grantedBy?: string
+  createdAt: number
+}
+
+export interface RoleResult {
+  status: boolean
+  roles?: RoleGrant[]
+  error?: string
+}
+
+export type JobState = 'queued' | 'running' | 'done' | 'failed'
+
+export interface JobRecord {
+  id: string
+  kind: 'download' | 'enhance' | 'chatbot' | 'media' | 'broadcast' | 'scheduled' | 'plugin'
+  chat: string
+  sender: string
+  state: JobState
+  error?: string
+  attempts: number
+  createdAt: number
+  updatedAt: number
+}
+
+export interface JobsResult {
+  status: boolean
+  jobs?: JobRecord[]
+  error?: string
+}
+
+export interface LocationInfo {
+  latitude: number
+  longitude: number
+  name?: string
+  address?: string
+  url?: string
+  comment?: string
+  caption?: string
+  accuracy?: number
+  speed?: number
+  heading?: number
+  sequence?: number
+  isLive: boolean
+}
+
+export interface ContactInfo {
+  displayName: string
+  vcard: string
+  phones: string[]
+}
+
+export interface PollResult {
+  status: boolean
+  result?: {
+    pollId: string
+    chat: string
+    name: string
+    selectable: number
+    totalVoters: number
+    options: Array<{
+      name: string
+      votes: number
+      voters: string[]
+    }>
+  }
+  error?: string
 }
 
 export interface DownloadResult {
@@ -46,14 +250,106 @@ export interface DownloadResult {
   result?: {
     video?: string
     images?: string[]
+    imageCount?: number
+    author?: string
+    authorName?: string
+    authorAvatar?: string
+    cover?: string
+    hd?: string
+    hdSize?: number
+    videoSize?: number
+    playCount?: number
+    likeCount?: number
+    commentCount?: number
+    shareCount?: number
+    items?: Array<{
+      type: 'image' | 'video' | 'gif'
+      url: string
+      thumbnail?: string
+      variants?: VideoVariant[]
+    }>
+    variants?: VideoVariant[]
+    isGif?: boolean
+    artists?: string[]
+    album?: string
+    youtubeUrl?: string
     music?: string
     wm?: string
     url?: string
     title?: string
     duration?: number
+    quality?: string
+    size?: number
+    downgraded?: boolean
     [key: string]: any
   }
   error?: string
+  code?: FetchErrorCode
+  blocked?: BlockedAddress
+}
+
+export type FetchErrorCode = 'invalid_url' | 'blocked_address' | 'request_failed' | 'bad_status' | 'too_large' | 'wrong_type'
+
+export interface BlockedAddress {
+  host: string
+  ip: string
+  reason: string
+}
+
+export interface VideoVariant {
+  url: string
+  contentType?: string
+  quality?: string
+  bitrate?: number
+  width?: number
+  height?: number
+}
+
+export interface MediaFormat {
+  quality: string
+  label: string
+  estimatedSize: number
+}
+
+export interface FormatsResult {
+  status: boolean
+  result?: {
+    title: string
+    duration: number
+    thumbnail: string
+    video: MediaFormat[]
+    audio: MediaFormat[]
+  }
+  error?: string
+}
+
+export interface VideoSearchItem {
+  rank: number
+  id: string
+  url: string
+  title: string
+  channel: string
+  duration: number
+  views: number
+  thumbnail: string
+}
+
+export interface ImageSearchItem {
+  url: string
+  title?: string
+  description?: string
+  width?: number
+  height?: number
+  source: string
+  pageUrl?: string
+}
+
+export interface SearchResult<T = VideoSearchItem> {
+  status: boolean
+  provider?: string
+  query?: string
+  result?: T[]
+  error?: string
 }
 
 export interface AIResponse {
@@ -77,6 +373,9 @@ export interface CommandContext {
   isImage?: boolean
   isQuotedImage?: boolean
   quotedMessage?: QuotedMessage
+  mediaType?: string
+  location?: LocationInfo
+  contacts?: ContactInfo[]
 }
 
 export interface QuotedMessage {
@@ -107,6 +406,8 @@ export interface Command {
   category: string
   description?: string
   wait?: boolean
+  // 'owner', 'admin' or a granted role; checked before the handler runs
+  role?: string
   handler: (
     bot: Bot,
     args: string[],